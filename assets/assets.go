@@ -0,0 +1,9 @@
+// Package assets embeds the assets of the application, so that the binary may be run from anywhere.
+package assets
+
+import "embed"
+
+// Icons are the icons representing each resource, named after the resource e.g. "icons/pods.png".
+//
+//go:embed icons/*.png
+var Icons embed.FS
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+	"github.com/AyCarlito/kube-visualization/pkg/snapshot"
+	"github.com/AyCarlito/kube-visualization/pkg/visualizer"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+// diffCmd is the command for visualising the changes to resources in namespaces between two states.
+var diffCmd = &cobra.Command{
+	Use:   "diff BEFORE [AFTER]",
+	Short: "Generate a graph of the changes to resources in namespaces between two snapshots, or a snapshot and the cluster.",
+	Long: `Generate a graph of the changes to resources in namespaces between two states.
+
+BEFORE is a snapshot. AFTER is a second snapshot, or if omitted, the source selected by the flags e.g. the cluster or
+manifests provided through --from-files. The namespaces captured in BEFORE, and AFTER if a snapshot, are used unless
+--namespace, --namespace-selector or --all-namespaces is provided.
+Added objects and connections are drawn in green, removed ones in red and
+modified ones in orange.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(kubeContexts) > 1 || allContexts {
+			return fmt.Errorf("comparing several clusters is not supported")
+		}
+		before, err := snapshot.Load(args[0], snapshot.WithLabelSelector(labelSelector))
+		if err != nil {
+			return fmt.Errorf("failed to load snapshot: %v", err)
+		}
+
+		var after *source
+		if len(args) == 2 {
+			after, err = newSnapshotSource(cmd, args[1])
+		} else {
+			after, err = newSource(cmd)
+		}
+		if err != nil {
+			return err
+		}
+		// Compare the namespaces captured in the snapshots, unless explicitly selected.
+		if !namespacesSelected(cmd) {
+			compared := append([]string{}, before.Namespaces...)
+			if len(args) == 2 {
+				for _, namespace := range after.namespaces {
+					if !slices.Contains(compared, namespace) {
+						compared = append(compared, namespace)
+					}
+				}
+			}
+			after.namespaces = compared
+		}
+
+		// Check the output before gathering anything.
+		// Resources only configured before are also drawn, so resolve the icons of both configurations.
+		icons := &config.Config{Resources: append(append([]config.Resource{}, before.Config.Resources...), after.configuration.Resources...)}
+		grapher, err := newGrapher(cmd, icons)
+		if err != nil {
+			return err
+		}
+
+		return visualizer.NewVisualizer(cmd.Context(), after.lister, after.configuration, grapher, after.namespaces, outputFile, visualizer.WithConcurrency(concurrency), visualizer.WithAllNamespaces(after.allNamespaces)).Diff(before, before.Config)
+	},
+}
@@ -0,0 +1,8 @@
+// Command kubectl-visualize is the kubectl plugin "kubectl visualize".
+package main
+
+import "github.com/AyCarlito/kube-visualization/cmd"
+
+func main() {
+	cmd.ExecutePlugin()
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AyCarlito/kube-visualization/pkg/graph"
+)
+
+// ExecutePlugin executes the visualize command as the kubectl plugin "kubectl visualize", which kubectl runs when the
+// "kubectl-visualize" binary is on the PATH e.g. "kubectl visualize -n shop deploy/frontend".
+// The remaining commands are available beneath it e.g. "kubectl visualize serve".
+// As the plugin may be run from anywhere, the configuration embedded in the binary is used and the graph is written
+// to stdout, unless the CLI flags say otherwise.
+func ExecutePlugin() {
+	rootCmd.Use = "kubectl-visualize [TYPE/NAME | TYPE NAME]"
+	rootCmd.Annotations = map[string]string{cobra.CommandDisplayNameAnnotation: "kubectl visualize"}
+	rootCmd.Short = visualizeCmd.Short
+	rootCmd.Long = visualizeCmd.Long
+	rootCmd.Args = visualizeCmd.Args
+	rootCmd.RunE = visualizeCmd.RunE
+	rootCmd.Flags().AddFlagSet(visualizeCmd.Flags())
+	rootCmd.RemoveCommand(visualizeCmd)
+
+	setDefault("config", "")
+	setDefault("output", graph.Stdout)
+
+	Execute()
+}
+
+// setDefault replaces the default value of a persistent flag of the root command.
+func setDefault(name, value string) {
+	flag := rootCmd.PersistentFlags().Lookup(name)
+	err := flag.Value.Set(value)
+	if err != nil {
+		panic(fmt.Errorf("failed to set default of flag %s: %v", name, err))
+	}
+	flag.DefValue = value
+}
@@ -6,32 +6,69 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/AyCarlito/kube-visualization/pkg/logger"
 )
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&assetsBasePath, "assets", "assets/", "Path to assets directory.")
-	rootCmd.PersistentFlags().StringVar(&configurationFile, "config", "config/config.json", "Path to configuration file.")
-	rootCmd.PersistentFlags().StringVar(&namespace, "namespace", "default", "Namespace of resources.")
-	rootCmd.PersistentFlags().StringVar(&outputFile, "output", "assets/output.dot", "Path to output file.")
-	rootCmd.PersistentFlags().StringVar(&labelSelector, "label-selector", "", "Filter resources by label. Comma separated key-value pairs.")
+	rootCmd.PersistentFlags().StringVar(&assetsBasePath, "assets", "", "Path to a directory of custom icons, named after their resource e.g. \"pods.png\", overriding the built-in icons.")
+	rootCmd.PersistentFlags().StringVar(&iconCacheDir, "icon-cache", "", "Directory the built-in icons are written to, for the output to reference. The output is only portable if relative e.g. \"assets/.icons\". A directory in the user cache directory is used if empty.")
+	rootCmd.PersistentFlags().StringVar(&configurationFile, "config", "config/config.json", "Path to configuration file. The configuration embedded in the binary is used if empty.")
+	rootCmd.PersistentFlags().StringSliceVarP(&namespaces, "namespace", "n", nil, "Namespaces of resources. Comma separated. The namespace of the kubeconfig context is used for a cluster if unset, and \"default\" otherwise.")
+	rootCmd.PersistentFlags().StringVar(&namespaceSelector, "namespace-selector", "", "Visualize the namespaces matching a label selector instead of --namespace.")
+	rootCmd.PersistentFlags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Visualize every namespace instead of --namespace.")
+	rootCmd.PersistentFlags().StringVar(&outputFile, "output", "assets/output.dot", "Path to output file. Use \"-\" for stdout.")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "", "Format of the output file, one of dot, svg, png or pdf. Inferred from the output file extension if empty.")
+	rootCmd.PersistentFlags().StringVarP(&labelSelector, "label-selector", "l", "", "Filter resources by label. Comma separated key-value pairs.")
 	rootCmd.PersistentFlags().StringVar(&kubeConfigPath, "kubeconfig", "", "Path to a kubeconfig file.")
+	rootCmd.PersistentFlags().StringSliceVar(&kubeContexts, "context", nil, "Kubeconfig contexts of the clusters to visualize. Comma separated. The current context is used if empty.")
+	rootCmd.PersistentFlags().BoolVar(&allContexts, "all-contexts", false, "Visualize the cluster of every context in the kubeconfig instead of --context.")
+	// The kubectl flags overriding the kubeconfig e.g. --cluster, --user and --as. --context and --namespace are bound
+	// above, as they may select several contexts and namespaces.
+	kubeConfigFlags := clientcmd.RecommendedConfigOverrideFlags("")
+	kubeConfigFlags.CurrentContext.LongName = ""
+	kubeConfigFlags.ContextOverrideFlags.Namespace.LongName = ""
+	clientcmd.BindOverrideFlags(&kubeConfigOverrides, rootCmd.PersistentFlags(), kubeConfigFlags)
+	rootCmd.PersistentFlags().Float32Var(&qps, "qps", 50, "Sustained rate of requests per second to the API server, above which requests are throttled.")
+	rootCmd.PersistentFlags().IntVar(&burst, "burst", 100, "Number of requests to the API server allowed at once, in excess of --qps.")
+	rootCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 8, "Number of lists of resources to make at once when gathering objects.")
+	rootCmd.PersistentFlags().BoolVar(&discover, "discover", false, "Visualize every listable namespaced resource found through the discovery API instead of those in the configuration file.")
+	rootCmd.PersistentFlags().StringSliceVar(&discoveryDenylist, "discovery-denylist", []string{"events", "events.events.k8s.io", "componentstatuses"}, "Resources, in the form \"resource.group\", to exclude when discovering resources.")
+	rootCmd.PersistentFlags().BoolVar(&inferRanks, "infer-ranks", false, "Infer ranks from the relationships between objects instead of the configured ranks.")
+	rootCmd.PersistentFlags().StringVar(&snapshotFile, "snapshot", "", "Path to a snapshot to visualize instead of a cluster.")
+	rootCmd.PersistentFlags().StringVar(&fromFiles, "from-files", "", "Path to a manifest file or directory to visualize instead of a cluster. Use \"-\" for stdin.")
 }
 
 // CLI Flags
 var (
 	assetsBasePath    string
+	iconCacheDir      string
 	configurationFile string
 	outputFile        string
-	namespace         string
+	outputFormat      string
+	namespaces        []string
+	namespaceSelector string
+	allNamespaces     bool
 	labelSelector     string
 	kubeConfigPath    string
+	kubeContexts      []string
+	allContexts       bool
+	// kubeConfigOverrides are bound to the kubectl flags overriding the kubeconfig.
+	kubeConfigOverrides clientcmd.ConfigOverrides
+	qps                 float32
+	burst               int
+	concurrency         int
+	fromFiles           string
+	discover            bool
+	discoveryDenylist   []string
+	inferRanks          bool
+	snapshotFile        string
 )
 
 var rootCmd = &cobra.Command{
 	Use:           "kube-visualization",
-	Short:         "Allows resources in a given namespace in a Kubernetes cluster to be visualized.",
+	Short:         "Allows resources in given namespaces in a Kubernetes cluster to be visualized.",
 	SilenceErrors: true,
 	SilenceUsage:  true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
@@ -41,7 +78,9 @@ var rootCmd = &cobra.Command{
 			panic(fmt.Errorf("failed to build zap logger: %v", err))
 		}
 		cmd.SetContext(logger.ContextWithLogger(cmd.Context(), log))
-		cmd.Parent().SetContext(logger.ContextWithLogger(cmd.Parent().Context(), log))
+		if cmd.HasParent() {
+			cmd.Parent().SetContext(logger.ContextWithLogger(cmd.Parent().Context(), log))
+		}
 		return nil
 	},
 }
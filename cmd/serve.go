@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+	"github.com/AyCarlito/kube-visualization/pkg/graph"
+	"github.com/AyCarlito/kube-visualization/pkg/logger"
+	"github.com/AyCarlito/kube-visualization/pkg/server"
+	"github.com/AyCarlito/kube-visualization/pkg/visualizer"
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&address, "address", "127.0.0.1:8080", "Address on which to serve the viewer. The viewer is unauthenticated, so only listens locally by default.")
+	serveCmd.Flags().DurationVar(&watchDebounce, "watch-debounce", 2*time.Second, "Period without changes to wait for before updating the graph.")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// CLI Flags
+var (
+	address string
+)
+
+// serveCmd is the command for serving an interactive, live graph of resources in a Kubernetes cluster.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an interactive graph of resources in namespaces, updated as they change.",
+	Long: `Serve an interactive graph of resources in namespaces over HTTP, with pan and zoom, search, and object details.
+
+When visualizing a cluster, the resources are watched and the graph is pushed to the browser whenever they change.
+Manifests and snapshots are served as they are.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srcs, err := newSources(cmd)
+		if err != nil {
+			return err
+		}
+		src := srcs[0]
+
+		ctx, cxl := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer cxl()
+
+		// Only a cluster changes, so there is nothing to watch otherwise.
+		lister := src.lister
+		var changes <-chan struct{}
+		opts := []visualizer.OptFunc{visualizer.WithConcurrency(concurrency)}
+		if src.context != "" {
+			var clusters []visualizer.Cluster
+			clusters, changes, err = newClusters(ctx, srcs, true)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, visualizer.WithClusters(clusters))
+		} else if src.client != nil {
+			watcher, err := newWatcher(ctx, src)
+			if err != nil {
+				return err
+			}
+			lister, changes = client.Lister(watcher), watcher.Changes()
+		}
+
+		resolver, err := newResolver(ctx, src.configuration)
+		if err != nil {
+			return err
+		}
+		srv := server.NewServer(address, resolver)
+		errs := make(chan error, 1)
+		go func() {
+			logger.LoggerFromContext(ctx).Info("Serving on: " + address)
+			errs <- srv.ListenAndServe(ctx)
+			// Stop watching should the server fail.
+			cxl()
+		}()
+
+		// Icons are resolved through the server, which serves them to the browser in place of the filesystem.
+		err = visualizer.NewVisualizer(ctx, lister, src.configuration, graph.NewGraph(srv, "", graph.WithInferredRanks(inferRanks)), src.namespaces, "", append(opts, visualizer.WithPublisher(srv.Publish))...).Watch(changes, watchDebounce)
+		cxl()
+		if serveErr := <-errs; serveErr != nil {
+			return serveErr
+		}
+		return err
+	},
+}
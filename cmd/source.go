@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+	"github.com/AyCarlito/kube-visualization/pkg/logger"
+	"github.com/AyCarlito/kube-visualization/pkg/manifest"
+	"github.com/AyCarlito/kube-visualization/pkg/snapshot"
+)
+
+// source is where objects are gathered from, along with the configuration and namespaces to gather them with.
+type source struct {
+	// context is the kubeconfig context of the cluster, set when visualizing several clusters.
+	context string
+	lister  client.Lister
+	// client is set when objects are gathered from a Kubernetes cluster.
+	client        *client.Client
+	configuration *config.Config
+	namespaces    []string
+	// allNamespaces is set when the namespaces of a cluster are every namespace in it when the source was created.
+	allNamespaces bool
+	// served is set once the resources which the cluster does not serve have been skipped.
+	served bool
+}
+
+// skipUnserved skips the resources of the source which are absent from the GVRs discovered from the cluster.
+func (s *source) skipUnserved(ctx context.Context, gvrs map[schema.GroupVersionResource]bool) {
+	var unserved []config.Resource
+	s.configuration, unserved = s.configuration.Served(gvrs)
+	for _, resource := range unserved {
+		logger.LoggerFromContext(ctx).Info("Skipping resource not served by the cluster: " + resource.String())
+	}
+	s.served = true
+}
+
+// namespacesSelected returns whether the namespaces to visualize are explicitly selected by the CLI flags.
+func namespacesSelected(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("namespace") || namespaceSelector != "" || allNamespaces
+}
+
+// validateNamespaces returns an error if the namespaces to visualize are not selected in exactly one way.
+func validateNamespaces(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("namespace") && len(namespaces) == 0 {
+		return fmt.Errorf("at least one namespace is required")
+	}
+	if namespaceSelector != "" && allNamespaces {
+		return fmt.Errorf("a namespace selector cannot be used when visualizing all namespaces")
+	}
+	return nil
+}
+
+// newConfig returns the configuration file selected by the CLI flags, or the configuration embedded in the binary if
+// none is selected.
+func newConfig() (*config.Config, error) {
+	if configurationFile == "" {
+		return config.NewDefaultConfig()
+	}
+	return config.NewConfig(configurationFile)
+}
+
+// newSources returns a source for each of the clusters selected by the CLI flags when several contexts are selected.
+// Otherwise, the single source selected by newSource is returned.
+func newSources(cmd *cobra.Command) ([]*source, error) {
+	contexts := kubeContexts
+	if allContexts {
+		var err error
+		contexts, err = client.Contexts(kubeConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(contexts) == 0 {
+			return nil, fmt.Errorf("no contexts found in kubeconfig")
+		}
+	}
+	if len(contexts) <= 1 && !allContexts {
+		src, err := newSource(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return []*source{src}, nil
+	}
+
+	if snapshotFile != "" || fromFiles != "" {
+		return nil, fmt.Errorf("visualizing several contexts requires a cluster and cannot be used with manifests or snapshots")
+	}
+	err := validateNamespaces(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var sources []*source
+	for _, kubeContext := range contexts {
+		// Each cluster may serve different resources, so is gathered with its own configuration.
+		cfg, err := newConfig()
+		if err != nil {
+			return nil, err
+		}
+		src, err := newClusterSource(cmd, cfg, kubeContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create source for context %s: %v", kubeContext, err)
+		}
+		src.context = kubeContext
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// newSource returns the source selected by the CLI flags.
+// In order of precedence, objects are gathered from a snapshot, from manifests or from a Kubernetes cluster.
+func newSource(cmd *cobra.Command) (*source, error) {
+	err := validateNamespaces(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshotFile != "" {
+		return newSnapshotSource(cmd, snapshotFile)
+	}
+
+	cfg, err := newConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Manifests on the local filesystem take the place of the cluster when provided.
+	if fromFiles != "" {
+		if discover {
+			return nil, fmt.Errorf("resource discovery requires a cluster and cannot be used with manifests")
+		}
+		if namespaceSelector != "" {
+			return nil, fmt.Errorf("selecting namespaces by label requires a cluster and cannot be used with manifests")
+		}
+		// Manifests have no kubeconfig context, so the default namespace is visualized unless namespaces are selected.
+		manifestNamespaces := namespaces
+		if len(manifestNamespaces) == 0 {
+			manifestNamespaces = []string{metav1.NamespaceDefault}
+		}
+		// Objects without a namespace belong to the first namespace.
+		reader, err := manifest.NewReader(fromFiles, manifest.WithLabelSelector(labelSelector), manifest.WithDefaultNamespace(manifestNamespaces[0]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new manifest reader: %v", err)
+		}
+		s := &source{lister: reader, configuration: cfg, namespaces: manifestNamespaces}
+		if allNamespaces {
+			s.namespaces = reader.Namespaces()
+		}
+		return s, nil
+	}
+
+	// A single context may be selected, otherwise the current context is used.
+	var kubeContext string
+	if len(kubeContexts) == 1 {
+		kubeContext = kubeContexts[0]
+	}
+	return newClusterSource(cmd, cfg, kubeContext)
+}
+
+// newClusterSource returns a source gathering objects from the Kubernetes cluster of the kubeconfig context, or the
+// current context if empty.
+// As with kubectl, the namespace of the context is visualized unless namespaces are selected by the CLI flags.
+func newClusterSource(cmd *cobra.Command, cfg *config.Config, kubeContext string) (*source, error) {
+	ctx := cmd.Context()
+	client, err := client.NewClient(client.WithLabelSelector(labelSelector), client.WithKubeConfigPath(kubeConfigPath), client.WithContext(kubeContext), client.WithConfigOverrides(kubeConfigOverrides), client.WithQPS(qps), client.WithBurst(burst))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new client: %v", err)
+	}
+
+	// Unless discovering resources, discovery only serves to detect whether resources are namespaced, which they are
+	// assumed to be unless configured otherwise. It is skipped when every scope is configured, as in the default
+	// configuration.
+	var gvrs map[schema.GroupVersionResource]bool
+	if discover || !cfg.ScopesConfigured() {
+		gvrs, err = client.Discover()
+		if err != nil {
+			if discover {
+				return nil, err
+			}
+			logger.LoggerFromContext(ctx).Warn("Unable to detect whether resources are namespaced: " + err.Error())
+		}
+	}
+
+	// Discovered resources replace those in the configuration file, which then only serves to rank them.
+	if discover {
+		cfg = cfg.Discovered(gvrs, discoveryDenylist)
+	}
+	cfg.DetectScopes(gvrs)
+
+	s := &source{lister: client, client: client, configuration: cfg, namespaces: namespaces}
+	// Resources which are not served have no objects to list e.g. custom resources whose definition is not installed.
+	if gvrs != nil {
+		s.skipUnserved(ctx, gvrs)
+	}
+	if !namespacesSelected(cmd) {
+		s.namespaces = []string{client.DefaultNamespace()}
+	}
+	// Namespaces selected by label, or every namespace, are resolved once, so namespaces created or relabelled since
+	// are not visualized until run again, even when watching.
+	if namespaceSelector != "" || allNamespaces {
+		s.namespaces, err = client.Namespaces(ctx, namespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+		s.allNamespaces = allNamespaces
+	}
+	return s, nil
+}
+
+// newSnapshotSource returns a source gathering objects from the snapshot at path.
+// The configuration and namespaces recorded in the snapshot are used, unless explicitly overridden by flags.
+func newSnapshotSource(cmd *cobra.Command, path string) (*source, error) {
+	if namespaceSelector != "" {
+		return nil, fmt.Errorf("selecting namespaces by label requires a cluster and cannot be used with snapshots")
+	}
+	snap, err := snapshot.Load(path, snapshot.WithLabelSelector(labelSelector))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	s := &source{lister: snap, configuration: snap.Config, namespaces: snap.Namespaces}
+	if cmd.Flags().Changed("config") {
+		s.configuration, err = newConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cmd.Flags().Changed("namespace") && !allNamespaces {
+		s.namespaces = namespaces
+	}
+	return s, nil
+}
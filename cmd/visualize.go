@@ -1,35 +1,293 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/AyCarlito/kube-visualization/pkg/client"
 	"github.com/AyCarlito/kube-visualization/pkg/config"
 	"github.com/AyCarlito/kube-visualization/pkg/graph"
+	"github.com/AyCarlito/kube-visualization/pkg/icons"
+	"github.com/AyCarlito/kube-visualization/pkg/logger"
+	"github.com/AyCarlito/kube-visualization/pkg/snapshot"
 	"github.com/AyCarlito/kube-visualization/pkg/visualizer"
 )
 
 func init() {
+	visualizeCmd.Flags().StringVar(&saveSnapshotFile, "save-snapshot", "", "Path to a file in which to save a snapshot of the gathered objects.")
+	visualizeCmd.Flags().BoolVar(&watch, "watch", false, "Watch the cluster and visualize again whenever resources change.")
+	visualizeCmd.Flags().DurationVar(&watchDebounce, "watch-debounce", 2*time.Second, "Period without changes to wait for before visualizing again when watching.")
 	rootCmd.AddCommand(visualizeCmd)
 }
 
+// CLI Flags
+var (
+	saveSnapshotFile string
+	watch            bool
+	watchDebounce    time.Duration
+)
+
 // visualizeCmd is the command for visualising resources in a Kubernetes cluster.
 var visualizeCmd = &cobra.Command{
-	Use:   "visualize",
-	Short: "List resources in a namespace and generate a heirarchical graph of them.",
+	Use:   "visualize [TYPE/NAME | TYPE NAME]",
+	Short: "List resources in namespaces and generate a heirarchical graph of them.",
+	Long: `List resources in namespaces and generate a heirarchical graph of them.
+
+An object may be given in the same form as kubectl e.g. "deploy/frontend", in which case only the object and the
+objects related to it are visualized. Objects are mostly related through the Pods running in a cluster, so manifests
+without Pods relate little e.g. focusing on a Deployment in manifests shows the Deployment alone, as the Services
+selecting its Pods and the ConfigMaps mounted by them are connected to Pods only.`,
+	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.NewConfig(configurationFile)
+		opts, err := focusOpts(args)
 		if err != nil {
-			panic(err)
+			return err
 		}
+		opts = append(opts, visualizer.WithConcurrency(concurrency))
 
-		client, err := client.NewClient(client.WithLabelSelector(labelSelector), client.WithKubeConfigPath(kubeConfigPath))
+		srcs, err := newSources(cmd)
 		if err != nil {
-			panic(fmt.Errorf("failed to create new client: %v", err))
+			return err
 		}
+		src := srcs[0]
 
-		return visualizer.NewVisualizer(cmd.Context(), client, cfg, graph.NewGraph(assetsBasePath, outputFile), namespace, outputFile).Visualize()
+		// Check the output before gathering anything.
+		grapher, err := newGrapher(cmd, src.configuration)
+		if err != nil {
+			return err
+		}
+
+		if src.context != "" {
+			return visualizeClusters(cmd, srcs, grapher, opts)
+		}
+		if watch {
+			return watchSource(cmd, src, grapher, opts)
+		}
+
+		// Record the gathered objects when a snapshot is to be saved.
+		lister := src.lister
+		var recorder *snapshot.Recorder
+		if saveSnapshotFile != "" {
+			recorder = snapshot.NewRecorder(lister)
+			lister = recorder
+		}
+
+		opts = append(opts, visualizer.WithAllNamespaces(src.allNamespaces))
+		err = visualizer.NewVisualizer(cmd.Context(), lister, src.configuration, grapher, src.namespaces, outputFile, opts...).Visualize()
+		if err != nil {
+			return err
+		}
+
+		if recorder != nil {
+			logger.LoggerFromContext(cmd.Context()).Info("Saving snapshot: " + saveSnapshotFile)
+			err = recorder.Save(saveSnapshotFile, src.configuration, src.namespaces, labelSelector)
+			if err != nil {
+				return fmt.Errorf("failed to save snapshot: %v", err)
+			}
+		}
+		return nil
 	},
 }
+
+// focusOpts returns the options focusing the visualization on the object given by the arguments, in the same form as
+// kubectl e.g. "deploy/frontend" or "deploy frontend", if any.
+func focusOpts(args []string) ([]visualizer.OptFunc, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	resource, name, err := parseObject(args)
+	if err != nil {
+		return nil, err
+	}
+	return []visualizer.OptFunc{visualizer.WithFocus(resource, name)}, nil
+}
+
+// parseObject returns the resource and name of the object given by the arguments, in the form TYPE/NAME or TYPE NAME.
+func parseObject(args []string) (string, string, error) {
+	var resource, name string
+	if len(args) == 1 {
+		var ok bool
+		resource, name, ok = strings.Cut(args[0], "/")
+		if !ok {
+			return "", "", fmt.Errorf("a name is required to focus on %q, in the form TYPE/NAME or TYPE NAME", args[0])
+		}
+	} else {
+		resource, name = args[0], args[1]
+	}
+	if resource == "" || name == "" {
+		return "", "", fmt.Errorf("invalid object %q, expected the form TYPE/NAME or TYPE NAME", strings.Join(args, " "))
+	}
+	return resource, name, nil
+}
+
+// watchSource visualizes the source whenever resources change, until interrupted.
+func watchSource(cmd *cobra.Command, src *source, grapher *graph.Grapher, opts []visualizer.OptFunc) error {
+	if saveSnapshotFile != "" {
+		return fmt.Errorf("watching cannot be used when saving a snapshot")
+	}
+
+	ctx, cxl := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cxl()
+
+	watcher, err := newWatcher(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	return visualizer.NewVisualizer(ctx, watcher, src.configuration, grapher, src.namespaces, outputFile, opts...).Watch(watcher.Changes(), watchDebounce)
+}
+
+// visualizeClusters visualizes the clusters of several sources in a single graph, watching them if requested.
+func visualizeClusters(cmd *cobra.Command, srcs []*source, grapher *graph.Grapher, opts []visualizer.OptFunc) error {
+	if saveSnapshotFile != "" {
+		return fmt.Errorf("saving a snapshot of several clusters is not supported")
+	}
+
+	ctx, cxl := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cxl()
+
+	clusters, changes, err := newClusters(ctx, srcs, watch)
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts, visualizer.WithClusters(clusters))
+	v := visualizer.NewVisualizer(ctx, nil, srcs[0].configuration, grapher, nil, outputFile, opts...)
+	if watch {
+		return v.Watch(changes, watchDebounce)
+	}
+	return v.Visualize()
+}
+
+// newClusters returns the clusters of the sources, each listed through a started *client.Watcher if watching, along
+// with a channel which receives whenever any of the watched clusters change.
+func newClusters(ctx context.Context, srcs []*source, watching bool) ([]visualizer.Cluster, <-chan struct{}, error) {
+	var clusters []visualizer.Cluster
+	// A single buffered notification suffices, as any number of pending changes are handled the same way.
+	changes := make(chan struct{}, 1)
+	for _, src := range srcs {
+		lister := src.lister
+		if watching {
+			watcher, err := newWatcher(ctx, src)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to watch context %s: %v", src.context, err)
+			}
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-watcher.Changes():
+						select {
+						case changes <- struct{}{}:
+						default:
+						}
+					}
+				}
+			}()
+			lister = watcher
+		}
+		clusters = append(clusters, visualizer.Cluster{Name: src.context, Lister: lister, Configuration: src.configuration, Namespaces: src.namespaces, AllNamespaces: src.allNamespaces})
+	}
+	return clusters, changes, nil
+}
+
+// newWatcher returns a started *client.Watcher for the resources of the source.
+func newWatcher(ctx context.Context, src *source) (*client.Watcher, error) {
+	if src.client == nil {
+		return nil, fmt.Errorf("watching requires a cluster and cannot be used with manifests or snapshots")
+	}
+
+	// Resources which are not served cannot be watched, as their informers would never sync, so must be skipped even
+	// if discovery was not needed to detect their scopes.
+	if !src.served {
+		served, err := src.client.Discover()
+		if err != nil {
+			return nil, err
+		}
+		src.skipUnserved(ctx, served)
+	}
+
+	gvrs := []schema.GroupVersionResource{}
+	clusterScoped := []schema.GroupVersionResource{}
+	for _, resource := range src.configuration.Resources {
+		if !resource.IsNamespaced() {
+			clusterScoped = append(clusterScoped, resource.GroupVersionResource)
+			continue
+		}
+		gvrs = append(gvrs, resource.GroupVersionResource)
+	}
+	// Namespaces are watched for their labels, which NetworkPolicies select namespaces by.
+	if !slices.Contains(clusterScoped, client.NamespacesGVR) {
+		clusterScoped = append(clusterScoped, client.NamespacesGVR)
+	}
+	// Every namespace is watched at once, rather than each of them in turn.
+	namespaces := src.namespaces
+	if src.allNamespaces {
+		namespaces = nil
+	}
+	watcher, err := src.client.NewWatcher(gvrs, namespaces, clusterScoped)
+	if err != nil {
+		return nil, err
+	}
+	logger.LoggerFromContext(ctx).Info("Waiting for caches to sync")
+	err = watcher.Start(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start watching: %v", err)
+	}
+	return watcher, nil
+}
+
+// newGrapher returns a new *graph.Grapher writing to the output file in the output format.
+// When only the output format is given, the extension of the default output file is replaced to match it.
+func newGrapher(cmd *cobra.Command, cfg *config.Config) (*graph.Grapher, error) {
+	if outputFormat != "" && !cmd.Flags().Changed("output") && outputFile != graph.Stdout {
+		if extension := graph.Format(outputFormat).Extension(); extension != "" {
+			outputFile = strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + extension
+		}
+	}
+	format, err := graph.ResolveFormat(outputFormat, outputFile)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := newResolver(cmd.Context(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return graph.NewGraph(resolver, outputFile, graph.WithInferredRanks(inferRanks), graph.WithFormat(format)), nil
+}
+
+// newResolver returns a new *icons.Resolver for the configured resources, logging any resources without an icon of
+// their own.
+func newResolver(ctx context.Context, cfg *config.Config) (*icons.Resolver, error) {
+	log := logger.LoggerFromContext(ctx)
+
+	resolver, err := icons.NewResolver(assetsBasePath, cfg.Icons(), icons.WithCacheDir(iconCacheDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create icon resolver: %v", err)
+	}
+
+	for _, resource := range cfg.Resources {
+		_, source := resolver.Resolve(resource.GroupResource())
+		if resource.Icon != "" && source != icons.Configured {
+			log.Warn(fmt.Sprintf("Configured icon for %s not found: %s", resource.GroupResource().String(), resource.Icon))
+		}
+		switch source {
+		case icons.Group:
+			log.Info(fmt.Sprintf("No icon for %s, using the icon of its group", resource.GroupResource().String()))
+		case icons.Generic:
+			log.Warn(fmt.Sprintf("No icon for %s, using the generic icon", resource.GroupResource().String()))
+		}
+	}
+	return resolver, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import "testing"
+
+func TestFocusOpts(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantResource string
+		wantName     string
+		wantErr      bool
+	}{
+		{
+			name: "no object",
+		},
+		{
+			name:         "type and name separated by a slash",
+			args:         []string{"deploy/frontend"},
+			wantResource: "deploy",
+			wantName:     "frontend",
+		},
+		{
+			name:         "type and name as separate arguments",
+			args:         []string{"deployments.apps", "frontend"},
+			wantResource: "deployments.apps",
+			wantName:     "frontend",
+		},
+		{
+			name:    "type without a name",
+			args:    []string{"deploy"},
+			wantErr: true,
+		},
+		{
+			name:    "empty name",
+			args:    []string{"deploy/"},
+			wantErr: true,
+		},
+		{
+			name:    "empty type",
+			args:    []string{"", "frontend"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := focusOpts(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("focusOpts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			wantOpts := 0
+			if tt.wantName != "" {
+				wantOpts = 1
+			}
+			if len(opts) != wantOpts {
+				t.Fatalf("focusOpts() returned %d options, want %d", len(opts), wantOpts)
+			}
+			if wantOpts == 0 {
+				return
+			}
+			resource, name, _ := parseObject(tt.args)
+			if resource != tt.wantResource || name != tt.wantName {
+				t.Errorf("parseObject() = %q, %q, want %q, %q", resource, name, tt.wantResource, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Package config embeds the default configuration file, so that the binary may be run from anywhere.
+package config
+
+import _ "embed"
+
+// Default is the default configuration file, "config.json".
+//
+//go:embed config.json
+var Default []byte
@@ -3,19 +3,25 @@ package client
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 // requestTimeout defines the timeout before a context is cancelled when performing Kubernetes API operations.
 const requestTimeout = 5 * time.Second
 
+// NamespacesGVR is the GVR of Namespaces, which are cluster-scoped.
+var NamespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
 // OptFunc is a function that mutates a clientOpts
 type OptFunc func(*clientOpts)
 
@@ -23,6 +29,10 @@ type OptFunc func(*clientOpts)
 type clientOpts struct {
 	labelSelector  string
 	kubeConfigPath string
+	context        string
+	overrides      clientcmd.ConfigOverrides
+	qps            float32
+	burst          int
 }
 
 // defaultOpts return the default configuration options for a Client
@@ -30,6 +40,10 @@ func defaultOpts() clientOpts {
 	return clientOpts{
 		labelSelector:  "",
 		kubeConfigPath: "",
+		context:        "",
+		overrides:      clientcmd.ConfigOverrides{},
+		qps:            0,
+		burst:          0,
 	}
 }
 
@@ -47,28 +61,106 @@ func WithKubeConfigPath(p string) OptFunc {
 	}
 }
 
+// WithContext returns an optFunc to mutate the context configuration option of the Client.
+// The context is the kubeconfig context of the cluster to interact with. By default, the current context is used.
+func WithContext(c string) OptFunc {
+	return func(o *clientOpts) {
+		o.context = c
+	}
+}
+
+// WithConfigOverrides returns an optFunc to mutate the overrides configuration option of the Client.
+// The overrides take precedence over the kubeconfig e.g. the cluster, user or identity to impersonate, as with the
+// kubectl flags of the same name. A configured context takes precedence over the context of the overrides.
+func WithConfigOverrides(overrides clientcmd.ConfigOverrides) OptFunc {
+	return func(o *clientOpts) {
+		o.overrides = overrides
+	}
+}
+
+// WithQPS returns an optFunc to mutate the qps configuration option of the Client.
+// The QPS is the sustained rate of requests to the API server, above which requests are throttled. The client-go
+// default is used if zero.
+func WithQPS(qps float32) OptFunc {
+	return func(o *clientOpts) {
+		o.qps = qps
+	}
+}
+
+// WithBurst returns an optFunc to mutate the burst configuration option of the Client.
+// The burst is the number of requests to the API server allowed at once, in excess of the QPS. The client-go default
+// is used if zero.
+func WithBurst(burst int) OptFunc {
+	return func(o *clientOpts) {
+		o.burst = burst
+	}
+}
+
+// Lister lists the objects in a namespace for a given GVR.
+// An empty namespace lists the objects of a cluster-scoped GVR.
+// It is the source of objects for a visualization, and may be implemented by anything capable of producing them e.g.
+// a Kubernetes cluster, manifests on the local filesystem or objects already held in memory.
+type Lister interface {
+	List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error)
+}
+
+// AllNamespacesLister is a Lister which can also list the objects of a namespaced GVR in every namespace at once.
+// When visualizing every namespace, a single list across them is far cheaper than one for each namespace.
+type AllNamespacesLister interface {
+	Lister
+	ListAllNamespaces(ctx context.Context, gvr schema.GroupVersionResource) (*unstructured.UnstructuredList, error)
+}
+
+// ListerFunc is an adapter allowing an ordinary function to be used as a Lister.
+type ListerFunc func(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error)
+
+// List calls f(ctx, gvr, namespace).
+func (f ListerFunc) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	return f(ctx, gvr, namespace)
+}
+
+// Client is an AllNamespacesLister.
+var _ AllNamespacesLister = &Client{}
+
 // Client interacts with resources on a Kubernetes cluster.
 type Client struct {
-	client *dynamic.DynamicClient
-	opts   clientOpts
+	client    *dynamic.DynamicClient
+	discovery *discovery.DiscoveryClient
+	// namespace is the namespace of the kubeconfig context, as used by kubectl when no namespace is given.
+	namespace string
+	opts      clientOpts
 }
 
 // NewClient returns a new *Client.
-// An in-cluster REST configuration is fetched. If this fails, a local one is used in its place.
+// The REST configuration is loaded with the same precedence as kubectl: the kubeconfig path if not empty, otherwise
+// the KUBECONFIG environment variable or ~/.kube/config, with the overrides applied on top. An in-cluster REST
+// configuration is only used if none of these configure a cluster.
 func NewClient(opts ...OptFunc) (*Client, error) {
 	o := defaultOpts()
 	for _, fn := range opts {
 		fn(&o)
 	}
 
-	restConfiguration, err := rest.InClusterConfig()
+	kubeConfigLoadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	kubeConfigLoadingRules.ExplicitPath = o.kubeConfigPath
+	overrides := o.overrides
+	if o.context != "" {
+		overrides.CurrentContext = o.context
+	}
+	clientConfiguration := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(kubeConfigLoadingRules, &overrides)
+	restConfiguration, err := clientConfiguration.ClientConfig()
 	if err != nil {
-		kubeConfigLoadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-		kubeConfigLoadingRules.ExplicitPath = o.kubeConfigPath
-		restConfiguration, err = clientcmd.BuildConfigFromFlags("", kubeConfigLoadingRules.GetDefaultFilename())
-		if err != nil {
-			return nil, fmt.Errorf("failed to get REST configuration: %v", err)
-		}
+		return nil, fmt.Errorf("failed to get REST configuration: %v", err)
+	}
+	namespace, _, err := clientConfiguration.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace: %v", err)
+	}
+	if o.qps > 0 {
+		restConfiguration.QPS = o.qps
+	}
+	if o.burst > 0 {
+		restConfiguration.Burst = o.burst
 	}
 
 	dynamicClient, err := dynamic.NewForConfig(restConfiguration)
@@ -76,21 +168,132 @@ func NewClient(opts ...OptFunc) (*Client, error) {
 		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
 	}
 
-	return &Client{client: dynamicClient, opts: o}, nil
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfiguration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %v", err)
+	}
+
+	return &Client{client: dynamicClient, discovery: discoveryClient, namespace: namespace, opts: o}, nil
+}
+
+// DefaultNamespace returns the namespace of the kubeconfig context, or "default" if the context does not set one.
+func (c *Client) DefaultNamespace() string {
+	return c.namespace
+}
+
+// Discover returns the GVR of every resource that supports the list verb, at its preferred version, along with
+// whether it is namespaced.
+// Subresources are excluded. API groups that fail discovery e.g. an unavailable aggregated API are skipped rather
+// than failing the whole operation.
+func (c *Client) Discover() (map[schema.GroupVersionResource]bool, error) {
+	resourceLists, err := discovery.ServerPreferredResources(c.discovery)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover resources: %v", err)
+	}
+
+	listable := discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list"}}, resourceLists)
+	gvrs := make(map[schema.GroupVersionResource]bool)
+	for _, resourceList := range listable {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse group version: %v", err)
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			gvrs[groupVersion.WithResource(resource.Name)] = resource.Namespaced
+		}
+	}
+
+	return gvrs, nil
 }
 
 // List returns a list of objects in a namespace for a given GVK.
 // The full object definition is not returned, only the metadata.
+// An empty namespace lists cluster-scoped objects, to which the label selector does not apply as they are shared by
+// every namespace.
 func (c *Client) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	listOptions := metav1.ListOptions{}
+	if namespace != "" {
+		listOptions.LabelSelector = c.opts.labelSelector
+	}
+	return c.list(ctx, gvr, namespace, listOptions)
+}
+
+// ListAllNamespaces returns a list of the objects of a namespaced GVR in every namespace, to which the label selector
+// applies.
+func (c *Client) ListAllNamespaces(ctx context.Context, gvr schema.GroupVersionResource) (*unstructured.UnstructuredList, error) {
+	return c.list(ctx, gvr, metav1.NamespaceAll, metav1.ListOptions{LabelSelector: c.opts.labelSelector})
+}
+
+// list returns a list of objects in a namespace for a given GVK, or in every namespace if empty.
+func (c *Client) list(ctx context.Context, gvr schema.GroupVersionResource, namespace string, listOptions metav1.ListOptions) (*unstructured.UnstructuredList, error) {
 	// Timebox the API call.
 	timeoutCtx, cxl := context.WithTimeout(ctx, requestTimeout)
 	defer cxl()
 
 	// List the objects.
-	unstructuredList, err := c.client.Resource(gvr).Namespace(namespace).List(timeoutCtx, metav1.ListOptions{LabelSelector: c.opts.labelSelector})
+	unstructuredList, err := c.client.Resource(gvr).Namespace(namespace).List(timeoutCtx, listOptions)
+	// A resource that is not served has no objects e.g. a custom resource whose definition is not installed.
+	if apierrors.IsNotFound(err) {
+		return &unstructured.UnstructuredList{}, nil
+	}
+	// The error is wrapped so that the reason for the failure may be inspected e.g. a lack of permission.
 	if err != nil {
-		return nil, fmt.Errorf("failed to list objects: %v", err)
+		return nil, fmt.Errorf("failed to list objects: %w", err)
 	}
 
 	return unstructuredList, nil
 }
+
+// forbidden returns the error listing a GVR across the cluster if the Client is not permitted to do so, or nil.
+func (c *Client) forbidden(gvr schema.GroupVersionResource) error {
+	// Timebox the API call.
+	timeoutCtx, cxl := context.WithTimeout(context.Background(), requestTimeout)
+	defer cxl()
+
+	_, err := c.client.Resource(gvr).List(timeoutCtx, metav1.ListOptions{Limit: 1})
+	if apierrors.IsForbidden(err) {
+		return err
+	}
+	return nil
+}
+
+// Namespaces returns the names of the namespaces matching the label selector, in alphabetical order.
+// An empty label selector matches every namespace.
+func (c *Client) Namespaces(ctx context.Context, labelSelector string) ([]string, error) {
+	// Timebox the API call.
+	timeoutCtx, cxl := context.WithTimeout(ctx, requestTimeout)
+	defer cxl()
+
+	unstructuredList, err := c.client.Resource(NamespacesGVR).List(timeoutCtx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %v", err)
+	}
+
+	namespaces := []string{}
+	for _, item := range unstructuredList.Items {
+		namespaces = append(namespaces, item.GetName())
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// Contexts returns the names of the contexts in the kubeconfig, in alphabetical order.
+// The kubeconfig is found in the same way as by NewClient, unless the path is not empty.
+func Contexts(kubeConfigPath string) ([]string, error) {
+	kubeConfigLoadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	kubeConfigLoadingRules.ExplicitPath = kubeConfigPath
+	kubeConfig, err := kubeConfigLoadingRules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	contexts := []string{}
+	for name := range kubeConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// redacted replaces the values of sensitive fields in objects.
+const redacted = "REDACTED"
+
+// lastAppliedConfigurationAnnotation is the annotation in which kubectl records the last applied object, which
+// repeats any sensitive fields of the object.
+const lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Redact replaces the values of the sensitive fields of an object, leaving their keys: the data of a Secret, and the
+// last applied configuration recorded by kubectl, which repeats it.
+// Objects are redacted wherever they leave the process e.g. when served or saved in a snapshot.
+func Redact(object *unstructured.Unstructured) {
+	annotations := object.GetAnnotations()
+	if _, ok := annotations[lastAppliedConfigurationAnnotation]; ok {
+		annotations[lastAppliedConfigurationAnnotation] = redacted
+		object.SetAnnotations(annotations)
+	}
+
+	if object.GroupVersionKind().Group != "" || object.GetKind() != "Secret" {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		data, ok, _ := unstructured.NestedMap(object.Object, field)
+		if !ok {
+			continue
+		}
+		for key := range data {
+			data[key] = redacted
+		}
+		unstructured.SetNestedMap(object.Object, data, field)
+	}
+}
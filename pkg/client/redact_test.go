@@ -0,0 +1,73 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name   string
+		object map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name: "secret data",
+			object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"data":       map[string]interface{}{"password": "aHVudGVyMg=="},
+				"stringData": map[string]interface{}{"token": "hunter2"},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"data":       map[string]interface{}{"password": redacted},
+				"stringData": map[string]interface{}{"token": redacted},
+			},
+		},
+		{
+			name: "last applied configuration",
+			object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{lastAppliedConfigurationAnnotation: `{"data":{}}`, "team": "a"},
+				},
+				"data": map[string]interface{}{"key": "value"},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{lastAppliedConfigurationAnnotation: redacted, "team": "a"},
+				},
+				"data": map[string]interface{}{"key": "value"},
+			},
+		},
+		{
+			name: "secret of another group",
+			object: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Secret",
+				"data":       map[string]interface{}{"key": "value"},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Secret",
+				"data":       map[string]interface{}{"key": "value"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := &unstructured.Unstructured{Object: tt.object}
+			Redact(object)
+			if !reflect.DeepEqual(object.Object, tt.want) {
+				t.Errorf("Redact() = %v, want %v", object.Object, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// syncTimeout defines the timeout for the informer caches to be populated before a Watcher fails to start.
+const syncTimeout = 30 * time.Second
+
+// Watcher is a Lister.
+var _ Lister = &Watcher{}
+
+// Watcher serves objects from informer caches kept up to date by watching a Kubernetes cluster, and notifies of any
+// changes to them.
+type Watcher struct {
+	factories []dynamicinformer.DynamicSharedInformerFactory
+	// informers are keyed by namespace. Cluster-scoped GVRs are watched under the empty namespace, as are namespaced
+	// GVRs when every namespace is watched.
+	informers map[string]map[schema.GroupVersionResource]informers.GenericInformer
+	// allNamespaces is set when every namespace is watched.
+	allNamespaces bool
+	// handlers are the registrations of the event handlers notifying of changes, which are synced once every object
+	// initially listed has been delivered to them.
+	handlers []cache.ResourceEventHandlerRegistration
+	// forbidden are the errors listing the cluster-scoped GVRs which are not permitted, and so are not watched.
+	forbidden map[schema.GroupVersionResource]error
+	changes   chan struct{}
+}
+
+// NewWatcher returns a new *Watcher for the GVRs in each of the namespaces, and the cluster-scoped GVRs across the
+// cluster. Nil namespaces watch the GVRs in every namespace through a single informer each, rather than one for each
+// namespace.
+// The label selector of the Client applies to the watched objects, other than those that are cluster-scoped.
+// Cluster-scoped GVRs which the Client is not permitted to list e.g. when only granted access to the namespaces, are
+// not watched, as their informers would never sync. Listing them fails as it would from the cluster.
+func (c *Client) NewWatcher(gvrs []schema.GroupVersionResource, namespaces []string, clusterScoped []schema.GroupVersionResource) (*Watcher, error) {
+	w := &Watcher{
+		informers:     make(map[string]map[schema.GroupVersionResource]informers.GenericInformer),
+		allNamespaces: namespaces == nil,
+		forbidden:     make(map[schema.GroupVersionResource]error),
+		// A single buffered notification suffices, as any number of pending changes are handled the same way.
+		changes: make(chan struct{}, 1),
+	}
+
+	notify := func() {
+		select {
+		case w.changes <- struct{}{}:
+		default:
+		}
+	}
+	watch := func(namespace string, factory dynamicinformer.DynamicSharedInformerFactory, gvrs []schema.GroupVersionResource) error {
+		w.factories = append(w.factories, factory)
+		if w.informers[namespace] == nil {
+			w.informers[namespace] = make(map[schema.GroupVersionResource]informers.GenericInformer)
+		}
+		for _, gvr := range gvrs {
+			informer := factory.ForResource(gvr)
+			handler, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(obj interface{}) { notify() },
+				UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+				DeleteFunc: func(obj interface{}) { notify() },
+			})
+			if err != nil {
+				return fmt.Errorf("failed to watch %s: %v", gvr.String(), err)
+			}
+			w.handlers = append(w.handlers, handler)
+			w.informers[namespace][gvr] = informer
+		}
+		return nil
+	}
+	if w.allNamespaces {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	for _, namespace := range namespaces {
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.client, 0, namespace, func(o *metav1.ListOptions) {
+			o.LabelSelector = c.opts.labelSelector
+		})
+		err := watch(namespace, factory, gvrs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var permitted []schema.GroupVersionResource
+	for _, gvr := range clusterScoped {
+		err := c.forbidden(gvr)
+		if err != nil {
+			w.forbidden[gvr] = err
+			continue
+		}
+		permitted = append(permitted, gvr)
+	}
+	err := watch("", dynamicinformer.NewDynamicSharedInformerFactory(c.client, 0), permitted)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Start starts watching until the context is cancelled, and waits for the informer caches to be populated.
+// The objects initially listed into the caches are not changes, so are not notified of.
+func (w *Watcher) Start(ctx context.Context) error {
+	for _, factory := range w.factories {
+		factory.Start(ctx.Done())
+	}
+
+	syncCtx, cxl := context.WithTimeout(ctx, syncTimeout)
+	defer cxl()
+	for _, factory := range w.factories {
+		for gvr, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+			if !synced {
+				return fmt.Errorf("failed to sync %s", gvr.String())
+			}
+		}
+	}
+
+	// Wait for the initial objects to be delivered to the event handlers before discarding their notification.
+	for _, handler := range w.handlers {
+		if !cache.WaitForCacheSync(syncCtx.Done(), handler.HasSynced) {
+			return fmt.Errorf("failed to sync event handlers")
+		}
+	}
+	select {
+	case <-w.changes:
+	default:
+	}
+	return nil
+}
+
+// Changes returns a channel which receives whenever a watched object is added, updated or deleted.
+// Notifications are coalesced, so a single receive may represent many changes.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// List returns a list of objects in a namespace for a given GVR from the informer cache.
+// Only watched GVRs may be listed, in watched namespaces. Cluster-scoped GVRs are listed with an empty namespace.
+func (w *Watcher) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	if err, ok := w.forbidden[gvr]; ok && namespace == "" {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	watchedNamespace := namespace
+	if w.allNamespaces {
+		watchedNamespace = metav1.NamespaceAll
+	}
+	informer, ok := w.informers[watchedNamespace][gvr]
+	if !ok {
+		return nil, fmt.Errorf("%s is not watched in namespace %q", gvr.String(), namespace)
+	}
+
+	var objects []runtime.Object
+	var err error
+	if namespace == "" {
+		objects, err = informer.Lister().List(labels.Everything())
+	} else {
+		objects, err = informer.Lister().ByNamespace(namespace).List(labels.Everything())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %v", err)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	for _, object := range objects {
+		u, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object type %T", object)
+		}
+		list.Items = append(list.Items, *u.DeepCopy())
+	}
+	// The cache is unordered, whereas the API server lists objects by name.
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].GetName() < list.Items[j].GetName()
+	})
+	return list, nil
+}
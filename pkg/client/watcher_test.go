@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	pods  = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	nodes = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+)
+
+// newFakeCluster returns a *Client of an API server serving a single Pod in the namespace "shop", and forbidding
+// Nodes from being listed. Pods sent to the returned channel are added through every open watch.
+func newFakeCluster(t *testing.T) (*Client, chan<- string) {
+	t.Helper()
+	added := make(chan string)
+	pod := func(name string) string {
+		return fmt.Sprintf(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":%q,"namespace":"shop","resourceVersion":"2"}}`, name)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/nodes":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Forbidden","code":403}`)
+		case r.URL.Path == "/api/v1/namespaces/shop/pods" && r.URL.Query().Get("watch") == "true":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case name := <-added:
+					fmt.Fprintf(w, `{"type":"ADDED","object":%s}`+"\n", pod(name))
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case r.URL.Path == "/api/v1/namespaces/shop/pods":
+			fmt.Fprintf(w, `{"apiVersion":"v1","kind":"PodList","metadata":{"resourceVersion":"1"},"items":[%s]}`, pod("web"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create dynamic client: %v", err)
+	}
+	return &Client{client: client}, added
+}
+
+func TestWatcherChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		// added is the name of a Pod added once the Watcher has started, if any.
+		added       string
+		wantChanged bool
+		wantNames   []string
+	}{
+		{
+			name:      "objects initially listed are not changes",
+			wantNames: []string{"web"},
+		},
+		{
+			name:        "added object is a change",
+			added:       "db",
+			wantChanged: true,
+			wantNames:   []string{"db", "web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, added := newFakeCluster(t)
+			ctx, cxl := context.WithCancel(context.Background())
+			// Stop watching before the fake cluster is closed, which waits for every watch to finish.
+			t.Cleanup(cxl)
+
+			w, err := c.NewWatcher([]schema.GroupVersionResource{pods}, []string{"shop"}, nil)
+			if err != nil {
+				t.Fatalf("NewWatcher() error = %v", err)
+			}
+			err = w.Start(ctx)
+			if err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+
+			var changed bool
+			if tt.added != "" {
+				added <- tt.added
+				select {
+				case <-w.Changes():
+					changed = true
+				case <-time.After(5 * time.Second):
+				}
+			} else {
+				select {
+				case <-w.Changes():
+					changed = true
+				default:
+				}
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Changes() notified = %v, want %v", changed, tt.wantChanged)
+			}
+
+			list, err := w.List(ctx, pods, "shop")
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var names []string
+			for _, object := range list.Items {
+				names = append(names, object.GetName())
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("List() = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestWatcherForbidden(t *testing.T) {
+	c, _ := newFakeCluster(t)
+	ctx, cxl := context.WithCancel(context.Background())
+	t.Cleanup(cxl)
+
+	w, err := c.NewWatcher([]schema.GroupVersionResource{pods}, []string{"shop"}, []schema.GroupVersionResource{nodes})
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	// An informer of a forbidden resource would never sync, failing to start.
+	err = w.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, ok := w.informers[""][nodes]; ok {
+		t.Errorf("NewWatcher() watched forbidden %s", nodes.String())
+	}
+	_, err = w.List(ctx, nodes, "")
+	if err == nil {
+		t.Errorf("List() of forbidden %s error = nil, want an error", nodes.String())
+	}
+}
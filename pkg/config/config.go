@@ -8,6 +8,8 @@ import (
 	"sort"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	embedded "github.com/AyCarlito/kube-visualization/config"
 )
 
 // Resource represents a ranked GVR.
@@ -15,6 +17,16 @@ type Resource struct {
 	schema.GroupVersionResource
 	// Rank identifies where the GVR should be ranked in the heirarchical visualization.
 	Rank int `json:"rank"`
+	// Icon is the path to an icon representing the GVR, or the name of another icon e.g. "ingresses.png", overriding
+	// the icon that would otherwise be used.
+	Icon string `json:"icon,omitempty"`
+	// Namespaced identifies whether objects of the GVR belong to a namespace, or to the cluster as a whole.
+	Namespaced *bool `json:"namespaced,omitempty"`
+}
+
+// IsNamespaced returns whether objects of the GVR belong to a namespace, which they do unless configured otherwise.
+func (r Resource) IsNamespaced() bool {
+	return r.Namespaced == nil || *r.Namespaced
 }
 
 // uniqueRanks returns the unique ranks of the Resources.
@@ -22,6 +34,9 @@ func uniqueRanks(resources []Resource) []int {
 	uniqueRanks := []int{}
 	existingRanks := make(map[int]struct{})
 	for _, resource := range resources {
+		if _, ok := existingRanks[resource.Rank]; ok {
+			continue
+		}
 		existingRanks[resource.Rank] = struct{}{}
 		uniqueRanks = append(uniqueRanks, resource.Rank)
 	}
@@ -55,10 +70,177 @@ func NewConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read configuration file: %v", err)
 	}
 
+	return unmarshalConfig(fileBytes)
+}
+
+// NewDefaultConfig returns a new Config from the default configuration file embedded in the binary.
+func NewDefaultConfig() (*Config, error) {
+	return unmarshalConfig(embedded.Default)
+}
+
+// unmarshalConfig returns a new Config from the contents of a configuration file.
+func unmarshalConfig(fileBytes []byte) (*Config, error) {
 	config := &Config{}
-	err = json.Unmarshal(fileBytes, config)
+	err := json.Unmarshal(fileBytes, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal configuration file: %v", err)
 	}
 	return config, nil
 }
+
+// referencedClusterScoped are the cluster-scoped resources whose objects are referenced from within namespaces e.g. the
+// Node a Pod is scheduled to. Other cluster-scoped objects are never drawn, so their resources are not discovered.
+var referencedClusterScoped = map[schema.GroupResource]struct{}{
+	{Resource: "nodes"}:                                              {},
+	{Resource: "persistentvolumes"}:                                  {},
+	{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}:   {},
+	{Group: "networking.k8s.io", Resource: "ingressclasses"}:         {},
+	{Group: "scheduling.k8s.io", Resource: "priorityclasses"}:        {},
+	{Group: "storage.k8s.io", Resource: "storageclasses"}:            {},
+	{Group: "gateway.networking.k8s.io", Resource: "gatewayclasses"}: {},
+}
+
+// Discovered returns a new Config containing the discovered GVRs which are namespaced or referenced from within
+// namespaces, less any whose resource is in the denylist. Discovered GVRs are keyed by GVR, with whether each is
+// namespaced. Cluster-scoped resources such as CustomResourceDefinitions are excluded, as listing them across the
+// cluster is costly and their objects are never drawn.
+// Entries in the denylist take the form "resource.group" e.g. "events.events.k8s.io", or "resource" for the core
+// group e.g. "events".
+// The rank and icon of a discovered GVR are taken from the resource of the same group in the Config, where present,
+// regardless of version. Otherwise, the GVR is ranked beneath every configured resource, sharing a rank with the other
+// unconfigured resources of its API group, so that the resources of each group e.g. of an operator occupy their own
+// row. Ranks may instead be inferred from the relationships between objects, as by graph.WithInferredRanks.
+func (c *Config) Discovered(gvrs map[schema.GroupVersionResource]bool, denylist []string) *Config {
+	denied := make(map[schema.GroupResource]struct{})
+	for _, entry := range denylist {
+		denied[schema.ParseGroupResource(entry)] = struct{}{}
+	}
+	// excluded returns whether a discovered GVR is left out of the Config.
+	excluded := func(gvr schema.GroupVersionResource, namespaced bool) bool {
+		if _, ok := denied[gvr.GroupResource()]; ok {
+			return true
+		}
+		_, referenced := referencedClusterScoped[gvr.GroupResource()]
+		return !namespaced && !referenced
+	}
+
+	known := make(map[schema.GroupResource]Resource)
+	highestRank := 0
+	for _, resource := range c.Resources {
+		known[resource.GroupResource()] = resource
+		highestRank = max(highestRank, resource.Rank)
+	}
+
+	// Rank the API groups of the unconfigured resources in alphabetical order, for a stable visualization.
+	unknownGroups := []string{}
+	seenGroups := make(map[string]struct{})
+	for gvr, namespaced := range gvrs {
+		if excluded(gvr, namespaced) {
+			continue
+		}
+		if _, ok := known[gvr.GroupResource()]; ok {
+			continue
+		}
+		if _, ok := seenGroups[gvr.Group]; ok {
+			continue
+		}
+		seenGroups[gvr.Group] = struct{}{}
+		unknownGroups = append(unknownGroups, gvr.Group)
+	}
+	sort.Strings(unknownGroups)
+	unknownRanks := make(map[string]int)
+	for i, group := range unknownGroups {
+		unknownRanks[group] = highestRank + 10*(i+1)
+	}
+
+	discovered := &Config{}
+	for gvr, namespaced := range gvrs {
+		if excluded(gvr, namespaced) {
+			continue
+		}
+		resource, ok := known[gvr.GroupResource()]
+		if !ok {
+			resource = Resource{Rank: unknownRanks[gvr.Group]}
+		}
+		discovered.Resources = append(discovered.Resources, Resource{
+			GroupVersionResource: gvr,
+			Rank:                 resource.Rank,
+			Icon:                 resource.Icon,
+			Namespaced:           &namespaced,
+		})
+	}
+
+	// Discovery order is not guaranteed, so sort for a stable visualization.
+	sort.Slice(discovered.Resources, func(i, j int) bool {
+		a, b := discovered.Resources[i], discovered.Resources[j]
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Resource < b.Resource
+	})
+	return discovered
+}
+
+// DetectScopes sets whether each resource is namespaced from the discovered GVRs, keyed by GVR with whether each is
+// namespaced, unless already configured. Resources are matched on group and resource, regardless of version, as the
+// scope of a resource is the same across its versions.
+func (c *Config) DetectScopes(gvrs map[schema.GroupVersionResource]bool) {
+	scopes := make(map[schema.GroupResource]bool)
+	for gvr, namespaced := range gvrs {
+		scopes[gvr.GroupResource()] = namespaced
+	}
+	for i, resource := range c.Resources {
+		if resource.Namespaced != nil {
+			continue
+		}
+		if namespaced, ok := scopes[resource.GroupResource()]; ok {
+			c.Resources[i].Namespaced = &namespaced
+		}
+	}
+}
+
+// ScopesConfigured returns whether every resource is configured as namespaced or not, so that no scope need be
+// detected.
+func (c *Config) ScopesConfigured() bool {
+	for _, resource := range c.Resources {
+		if resource.Namespaced == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Served returns a copy of the Config without the resources absent from the discovered GVRs, along with the resources
+// removed e.g. custom resources whose definition is not installed in the cluster. Resources are matched on group and
+// resource, regardless of version, as only the preferred version of each resource is discovered.
+func (c *Config) Served(gvrs map[schema.GroupVersionResource]bool) (*Config, []Resource) {
+	served := make(map[schema.GroupResource]struct{})
+	for gvr := range gvrs {
+		served[gvr.GroupResource()] = struct{}{}
+	}
+
+	filtered := &Config{}
+	var unserved []Resource
+	for _, resource := range c.Resources {
+		if _, ok := served[resource.GroupResource()]; !ok {
+			unserved = append(unserved, resource)
+			continue
+		}
+		filtered.Resources = append(filtered.Resources, resource)
+	}
+	return filtered, unserved
+}
+
+// Icons returns the icon configured for each resource, where present.
+func (c *Config) Icons() map[schema.GroupResource]string {
+	icons := make(map[schema.GroupResource]string)
+	for _, resource := range c.Resources {
+		if resource.Icon != "" {
+			icons[resource.GroupResource()] = resource.Icon
+		}
+	}
+	return icons
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	pods        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	events      = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	nodes       = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	widgets     = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	gadgets     = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}
+	certs       = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+)
+
+// scope returns a pointer to whether a resource is namespaced.
+func scope(namespaced bool) *bool {
+	return &namespaced
+}
+
+func TestDiscovered(t *testing.T) {
+	configured := &Config{Resources: []Resource{
+		{GroupVersionResource: pods, Rank: 30, Icon: "pod.png"},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v1beta1", Resource: "deployments"}, Rank: 10},
+	}}
+	tests := []struct {
+		name     string
+		gvrs     map[schema.GroupVersionResource]bool
+		denylist []string
+		want     []Resource
+	}{
+		{
+			name: "configured rank and icon are kept regardless of version",
+			gvrs: map[schema.GroupVersionResource]bool{pods: true, deployments: true},
+			want: []Resource{
+				{GroupVersionResource: deployments, Rank: 10, Namespaced: scope(true)},
+				{GroupVersionResource: pods, Rank: 30, Icon: "pod.png", Namespaced: scope(true)},
+			},
+		},
+		{
+			name: "unconfigured resources are ranked beneath by API group",
+			gvrs: map[schema.GroupVersionResource]bool{pods: true, nodes: false, widgets: true, gadgets: true, certs: true},
+			want: []Resource{
+				{GroupVersionResource: pods, Rank: 30, Icon: "pod.png", Namespaced: scope(true)},
+				{GroupVersionResource: nodes, Rank: 40, Namespaced: scope(false)},
+				{GroupVersionResource: certs, Rank: 50, Namespaced: scope(true)},
+				{GroupVersionResource: gadgets, Rank: 60, Namespaced: scope(true)},
+				{GroupVersionResource: widgets, Rank: 60, Namespaced: scope(true)},
+			},
+		},
+		{
+			name: "cluster-scoped resources are excluded unless referenced",
+			gvrs: map[schema.GroupVersionResource]bool{
+				pods:  true,
+				nodes: false,
+				{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}: false,
+				{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"}:  false,
+				{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}:         false,
+			},
+			want: []Resource{
+				{GroupVersionResource: pods, Rank: 30, Icon: "pod.png", Namespaced: scope(true)},
+				{GroupVersionResource: nodes, Rank: 40, Namespaced: scope(false)},
+				{GroupVersionResource: schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, Rank: 50, Namespaced: scope(false)},
+			},
+		},
+		{
+			name:     "denied resources are excluded",
+			gvrs:     map[schema.GroupVersionResource]bool{pods: true, events: true, widgets: true},
+			denylist: []string{"events", "widgets.example.com"},
+			want: []Resource{
+				{GroupVersionResource: pods, Rank: 30, Icon: "pod.png", Namespaced: scope(true)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := configured.Discovered(tt.gvrs, tt.denylist)
+			if !reflect.DeepEqual(got.Resources, tt.want) {
+				t.Errorf("Discovered() = %+v, want %+v", got.Resources, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectScopes(t *testing.T) {
+	c := &Config{Resources: []Resource{
+		{GroupVersionResource: pods},
+		{GroupVersionResource: schema.GroupVersionResource{Version: "v1beta1", Resource: "nodes"}},
+		{GroupVersionResource: widgets, Namespaced: scope(false)},
+		{GroupVersionResource: gadgets},
+	}}
+	c.DetectScopes(map[schema.GroupVersionResource]bool{pods: true, nodes: false, widgets: true})
+
+	tests := []struct {
+		name           string
+		resource       Resource
+		wantNamespaced bool
+		wantConfigured bool
+	}{
+		{name: "namespaced", resource: c.Resources[0], wantNamespaced: true, wantConfigured: true},
+		{name: "cluster-scoped regardless of version", resource: c.Resources[1], wantNamespaced: false, wantConfigured: true},
+		{name: "configured scope is kept", resource: c.Resources[2], wantNamespaced: false, wantConfigured: true},
+		{name: "undiscovered resource is assumed namespaced", resource: c.Resources[3], wantNamespaced: true, wantConfigured: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resource.IsNamespaced(); got != tt.wantNamespaced {
+				t.Errorf("IsNamespaced() = %v, want %v", got, tt.wantNamespaced)
+			}
+			if got := tt.resource.Namespaced != nil; got != tt.wantConfigured {
+				t.Errorf("DetectScopes() set scope = %v, want %v", got, tt.wantConfigured)
+			}
+		})
+	}
+}
+
+func TestScopesConfigured(t *testing.T) {
+	defaultConfig, err := NewDefaultConfig()
+	if err != nil {
+		t.Fatalf("NewDefaultConfig() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{
+			name:   "default configuration",
+			config: defaultConfig,
+			want:   true,
+		},
+		{
+			name:   "every scope configured",
+			config: &Config{Resources: []Resource{{GroupVersionResource: pods, Namespaced: scope(true)}, {GroupVersionResource: nodes, Namespaced: scope(false)}}},
+			want:   true,
+		},
+		{
+			name:   "scope unconfigured",
+			config: &Config{Resources: []Resource{{GroupVersionResource: pods, Namespaced: scope(true)}, {GroupVersionResource: widgets}}},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.ScopesConfigured(); got != tt.want {
+				t.Errorf("ScopesConfigured() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServed(t *testing.T) {
+	c := &Config{Resources: []Resource{
+		{GroupVersionResource: pods, Rank: 10},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v1beta1", Resource: "deployments"}, Rank: 20},
+		{GroupVersionResource: widgets, Rank: 30},
+	}}
+	tests := []struct {
+		name         string
+		gvrs         map[schema.GroupVersionResource]bool
+		wantServed   []Resource
+		wantUnserved []Resource
+	}{
+		{
+			name:       "every resource served, regardless of version",
+			gvrs:       map[schema.GroupVersionResource]bool{pods: true, deployments: true, widgets: true, nodes: false},
+			wantServed: c.Resources,
+		},
+		{
+			name:         "unserved resources are removed",
+			gvrs:         map[schema.GroupVersionResource]bool{pods: true, gadgets: true},
+			wantServed:   []Resource{c.Resources[0]},
+			wantUnserved: []Resource{c.Resources[1], c.Resources[2]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served, unserved := c.Served(tt.gvrs)
+			if !reflect.DeepEqual(served.Resources, tt.wantServed) {
+				t.Errorf("Served() = %v, want %v", served.Resources, tt.wantServed)
+			}
+			if !reflect.DeepEqual(unserved, tt.wantUnserved) {
+				t.Errorf("Served() unserved = %v, want %v", unserved, tt.wantUnserved)
+			}
+		})
+	}
+	if len(c.Resources) != 3 {
+		t.Errorf("Served() modified the Config, leaving %d resources", len(c.Resources))
+	}
+}
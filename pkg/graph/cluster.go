@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// cluster is a Kubernetes cluster to be represented in the graph, along with its namespaces.
+type cluster struct {
+	name       string
+	namespaces []string
+}
+
+// getClusterQualifiedName returns a name qualified by the cluster it belongs to e.g. "prod:default/web", unless
+// the cluster is empty.
+func getClusterQualifiedName(cluster, name string) string {
+	if cluster == "" {
+		return name
+	}
+	return fmt.Sprintf("%s:%s", cluster, name)
+}
+
+// getClusterSubgraphName returns the name of the subgraph of a cluster in a gographviz.Graph.
+// Graphviz draws a border around subgraphs whose name begins with "cluster".
+func getClusterSubgraphName(cluster string) string {
+	return getSanitizedObjectName(cluster, "cluster_cluster")
+}
+
+// getParentGraphName returns the name of the graph in which to place objects outside of the namespaces of a cluster,
+// which is the subgraph of the cluster if several clusters have been merged.
+func (g *Grapher) getParentGraphName(cluster string) string {
+	if cluster == "" {
+		return g.name
+	}
+	return getClusterSubgraphName(cluster)
+}
+
+// Merge populates the graph with the objects of several clusters, keyed by the name of each cluster, all of which
+// must have been scaffolded and populated, but not connected.
+// Each cluster is represented by a subgraph containing its namespaces. Objects of the same name in different
+// clusters are distinct, and are never connected to one another.
+func (g *Grapher) Merge(clusters map[string]*Grapher) {
+	names := []string{}
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	uniqueRanks := make(map[int]struct{})
+	for _, name := range names {
+		for _, rank := range clusters[name].ranks {
+			uniqueRanks[rank] = struct{}{}
+		}
+	}
+	ranks := []int{}
+	for rank := range uniqueRanks {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+
+	graphName := ""
+	if len(names) > 0 {
+		graphName = clusters[names[0]].name
+	}
+	g.Scaffold(graphName, nil, ranks)
+
+	for _, name := range names {
+		c := clusters[name]
+		c.resolve()
+		g.clusters = append(g.clusters, cluster{name: name, namespaces: c.namespaces})
+		for _, n := range c.nodes {
+			n.cluster = name
+			g.nodes = append(g.nodes, n)
+		}
+		for _, connection := range c.connections {
+			connection.cluster = name
+			g.connections = append(g.connections, connection)
+		}
+	}
+	g.resolved = true
+}
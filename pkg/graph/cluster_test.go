@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	pod := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+spec:
+  nodeName: node-1
+`
+	node := `
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-1
+`
+	tests := []struct {
+		name string
+		// manifests are the manifests of the objects in each cluster, each of which has the namespaces "shop" and
+		// the name of the cluster.
+		manifests       map[string]string
+		wantClusters    []cluster
+		wantObjects     []string
+		wantConnections []string
+	}{
+		{
+			name:      "objects of the same name in each cluster are distinct",
+			manifests: map[string]string{"staging": pod, "prod": pod},
+			wantClusters: []cluster{
+				{name: "prod", namespaces: []string{"shop", "prod"}},
+				{name: "staging", namespaces: []string{"shop", "staging"}},
+			},
+			wantObjects: []string{"Pod_prod:shop/web", "Pod_staging:shop/web"},
+			wantConnections: []string{
+				"Pod_prod:shop/web -> Node_prod:shop/node-1",
+				"ServiceAccount_prod:shop/default -> Pod_prod:shop/web",
+				"Pod_staging:shop/web -> Node_staging:shop/node-1",
+				"ServiceAccount_staging:shop/default -> Pod_staging:shop/web",
+			},
+		},
+		{
+			name:      "cluster-scoped objects are only referenced within their cluster",
+			manifests: map[string]string{"staging": pod, "prod": pod + node},
+			wantClusters: []cluster{
+				{name: "prod", namespaces: []string{"shop", "prod"}},
+				{name: "staging", namespaces: []string{"shop", "staging"}},
+			},
+			wantObjects: []string{"Node_prod:node-1", "Pod_prod:shop/web", "Pod_staging:shop/web"},
+			wantConnections: []string{
+				"Pod_prod:shop/web -> Node_prod:node-1",
+				"ServiceAccount_prod:shop/default -> Pod_prod:shop/web",
+				"Pod_staging:shop/web -> Node_staging:shop/node-1",
+				"ServiceAccount_staging:shop/default -> Pod_staging:shop/web",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := make(map[string]*Grapher)
+			for name, manifests := range tt.manifests {
+				clusters[name] = newPopulatedGrapher(t, []string{"shop", name}, manifests)
+			}
+			g := NewGraph(nil, "")
+			g.Merge(clusters)
+
+			if !slices.EqualFunc(g.clusters, tt.wantClusters, func(a, b cluster) bool {
+				return a.name == b.name && slices.Equal(a.namespaces, b.namespaces)
+			}) {
+				t.Errorf("Merge() clusters = %v, want %v", g.clusters, tt.wantClusters)
+			}
+			objects := slices.Sorted(maps.Keys(g.Objects()))
+			if !slices.Equal(objects, tt.wantObjects) {
+				t.Errorf("Merge() objects = %v, want %v", objects, tt.wantObjects)
+			}
+			connections := getConnections(g, func(c connection) bool { return true })
+			if !slices.Equal(connections, tt.wantConnections) {
+				t.Errorf("Merge() connections = %v, want %v", connections, tt.wantConnections)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+)
+
+// state is the state of a node or connection across two populations of a graph.
+type state int
+
+const (
+	// unchanged is present in both populations, without modification. It is also the state of every node and
+	// connection outside of a diff.
+	unchanged state = iota
+	// added is only present in the later population.
+	added
+	// removed is only present in the earlier population.
+	removed
+	// modified is present in both populations, with modification.
+	modified
+)
+
+// colors are the colors representing each state, other than unchanged.
+var colors = map[state]string{
+	added:    "green",
+	removed:  "red",
+	modified: "orange",
+}
+
+// decorateNode styles the attributes of a node named name according to the state.
+// Removed nodes have their name struck through.
+func (s state) decorateNode(name string, attrs map[string]string) {
+	color, ok := colors[s]
+	if !ok {
+		return
+	}
+	attrs["penwidth"] = "2"
+	attrs["color"] = color
+	attrs["fontcolor"] = color
+	if s == removed {
+		attrs["style"] = "dashed"
+		attrs["label"] = fmt.Sprintf("<%s<S>%s</S>>", strings.Repeat("<BR/>", 9), html.EscapeString(name))
+	}
+}
+
+// decorateEdge styles the attributes of an edge according to the state.
+func (s state) decorateEdge(attrs map[string]string) {
+	color, ok := colors[s]
+	if !ok {
+		return
+	}
+	attrs["penwidth"] = "2"
+	attrs["color"] = color
+	attrs["fontcolor"] = color
+}
+
+// volatileFields are the fields of an object which change without any change to the object's intent, and so are
+// ignored when determining whether an object has been modified.
+var volatileFields = [][]string{
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "uid"},
+	{"metadata", "creationTimestamp"},
+	{"status"},
+}
+
+// isModified returns whether the object has been modified from before to after.
+// Placeholders for objects outside of the namespaces have no object, and so are never modified. Sensitive fields are
+// redacted in snapshots, so are compared redacted e.g. a Secret is only modified if its keys change.
+func isModified(before, after *unstructured.Unstructured) bool {
+	if before == nil || after == nil {
+		return false
+	}
+	b, a := before.DeepCopy(), after.DeepCopy()
+	client.Redact(b)
+	client.Redact(a)
+	for _, field := range volatileFields {
+		unstructured.RemoveNestedField(b.Object, field...)
+		unstructured.RemoveNestedField(a.Object, field...)
+	}
+	return !reflect.DeepEqual(b.Object, a.Object)
+}
+
+// connectionKey identifies a connection regardless of its label.
+func connectionKey(c connection) string {
+	return c.sourceID() + "->" + c.destinationID()
+}
+
+// connectedConnections returns the connections of the grapher whose source and destination are both present in its
+// population. Connections to absent objects e.g. a Service to its deleted Endpoints, are never drawn, and so are not
+// compared; otherwise, such a connection would be unchanged while the object it was connected to is removed.
+func (g *Grapher) connectedConnections() []connection {
+	existing := make(map[string]struct{})
+	for _, n := range g.nodes {
+		existing[n.id()] = struct{}{}
+	}
+	var connections []connection
+	for _, c := range g.connections {
+		if _, ok := existing[c.sourceID()]; !ok {
+			continue
+		}
+		if _, ok := existing[c.destinationID()]; !ok {
+			continue
+		}
+		connections = append(connections, c)
+	}
+	return connections
+}
+
+// Diff populates the graph with the changes from before to after, both of which must have been scaffolded and
+// populated, but not connected.
+// Every node from both populations is present, along with every connection between nodes of the same population.
+// Those only in before are removed, those only in after are added, and those in both are modified if they differ in
+// any non-volatile field or label respectively.
+func (g *Grapher) Diff(before, after *Grapher) {
+	before.resolve()
+	after.resolve()
+
+	uniqueRanks := make(map[int]struct{})
+	for _, rank := range append(append([]int{}, before.ranks...), after.ranks...) {
+		uniqueRanks[rank] = struct{}{}
+	}
+	ranks := []int{}
+	for rank := range uniqueRanks {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	// A namespace present in either population is visualized e.g. one which has since been deleted.
+	namespaces := append([]string{}, after.namespaces...)
+	for _, namespace := range before.namespaces {
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	g.Scaffold(after.name, namespaces, ranks)
+
+	beforeNodes := make(map[string]node)
+	for _, n := range before.nodes {
+		beforeNodes[n.id()] = n
+	}
+	afterNodes := make(map[string]struct{})
+	for _, n := range after.nodes {
+		id := n.id()
+		afterNodes[id] = struct{}{}
+		n.state = added
+		if b, ok := beforeNodes[id]; ok {
+			n.state = unchanged
+			if isModified(b.object, n.object) {
+				n.state = modified
+			}
+		}
+		g.nodes = append(g.nodes, n)
+	}
+	for _, n := range before.nodes {
+		if _, ok := afterNodes[n.id()]; ok {
+			continue
+		}
+		n.state = removed
+		g.nodes = append(g.nodes, n)
+	}
+
+	beforeConnected := before.connectedConnections()
+	beforeConnections := make(map[string]connection)
+	for _, c := range beforeConnected {
+		beforeConnections[connectionKey(c)] = c
+	}
+	afterConnections := make(map[string]struct{})
+	for _, c := range after.connectedConnections() {
+		afterConnections[connectionKey(c)] = struct{}{}
+		c.state = added
+		if b, ok := beforeConnections[connectionKey(c)]; ok {
+			c.state = unchanged
+			if b.label != c.label {
+				c.state = modified
+			}
+		}
+		g.connections = append(g.connections, c)
+	}
+	for _, c := range beforeConnected {
+		if _, ok := afterConnections[connectionKey(c)]; ok {
+			continue
+		}
+		c.state = removed
+		g.connections = append(g.connections, c)
+	}
+	// Both populations have already been resolved.
+	g.resolved = true
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	service := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  ports:
+  - port: 80
+    protocol: TCP
+---
+`
+	endpoints := `
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web
+  namespace: shop
+---
+`
+	tests := []struct {
+		name            string
+		before          string
+		after           string
+		wantNodes       map[string]state
+		wantConnections map[string]state
+	}{
+		{
+			name:            "unchanged",
+			before:          service + endpoints,
+			after:           service + endpoints,
+			wantNodes:       map[string]state{"Service_shop/web": unchanged, "Endpoints_shop/web": unchanged},
+			wantConnections: map[string]state{"Service_shop/web -> Endpoints_shop/web": unchanged},
+		},
+		{
+			name:   "volatile fields are ignored",
+			before: service,
+			after: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+  resourceVersion: "2"
+spec:
+  ports:
+  - port: 80
+    protocol: TCP
+status:
+  loadBalancer: {}
+`,
+			wantNodes:       map[string]state{"Service_shop/web": unchanged},
+			wantConnections: map[string]state{},
+		},
+		{
+			name:            "added node and connection",
+			before:          service,
+			after:           service + endpoints,
+			wantNodes:       map[string]state{"Service_shop/web": unchanged, "Endpoints_shop/web": added},
+			wantConnections: map[string]state{"Service_shop/web -> Endpoints_shop/web": added},
+		},
+		{
+			name:            "removed node and the connection to it",
+			before:          service + endpoints,
+			after:           service,
+			wantNodes:       map[string]state{"Service_shop/web": unchanged, "Endpoints_shop/web": removed},
+			wantConnections: map[string]state{"Service_shop/web -> Endpoints_shop/web": removed},
+		},
+		{
+			name:   "modified node and connection",
+			before: service + endpoints,
+			after: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  ports:
+  - port: 8080
+    protocol: TCP
+---
+` + endpoints,
+			wantNodes:       map[string]state{"Service_shop/web": modified, "Endpoints_shop/web": unchanged},
+			wantConnections: map[string]state{"Service_shop/web -> Endpoints_shop/web": modified},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := newPopulatedGrapher(t, []string{"shop"}, tt.before)
+			after := newPopulatedGrapher(t, []string{"shop"}, tt.after)
+			g := NewGraph(nil, "")
+			g.Diff(before, after)
+
+			nodes := make(map[string]state)
+			for _, n := range g.nodes {
+				nodes[strings.Trim(n.id(), `"`)] = n.state
+			}
+			if !maps.Equal(nodes, tt.wantNodes) {
+				t.Errorf("Diff() nodes = %v, want %v", nodes, tt.wantNodes)
+			}
+			connections := make(map[string]state)
+			for _, c := range g.connections {
+				connections[strings.Trim(c.sourceID(), `"`)+" -> "+strings.Trim(c.destinationID(), `"`)] = c.state
+			}
+			if !maps.Equal(connections, tt.wantConnections) {
+				t.Errorf("Diff() connections = %v, want %v", connections, tt.wantConnections)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shortNames are the short names of the resources built into Kubernetes, as accepted by kubectl e.g. "deploy".
+// Custom resources may declare short names of their own, which are only known to the cluster, so are referenced by
+// their kind or resource instead.
+var shortNames = map[string]string{
+	"cj":     "cronjobs",
+	"cm":     "configmaps",
+	"csr":    "certificatesigningrequests",
+	"ds":     "daemonsets",
+	"deploy": "deployments",
+	"ep":     "endpoints",
+	"hpa":    "horizontalpodautoscalers",
+	"ing":    "ingresses",
+	"limits": "limitranges",
+	"netpol": "networkpolicies",
+	"no":     "nodes",
+	"ns":     "namespaces",
+	"pc":     "priorityclasses",
+	"pdb":    "poddisruptionbudgets",
+	"po":     "pods",
+	"pv":     "persistentvolumes",
+	"pvc":    "persistentvolumeclaims",
+	"quota":  "resourcequotas",
+	"rs":     "replicasets",
+	"sa":     "serviceaccounts",
+	"sc":     "storageclasses",
+	"sts":    "statefulsets",
+	"svc":    "services",
+}
+
+// matches returns whether the node is of the resource, as referenced by kubectl e.g. "deploy", "deployment",
+// "deployments" or "deployments.apps".
+func (n *node) matches(resource string) bool {
+	resource = strings.ToLower(resource)
+	if r, ok := shortNames[resource]; ok && isBuiltInGroup(n.resource.Group) {
+		resource = r
+	}
+	name, group, _ := strings.Cut(resource, ".")
+	if group != "" && group != n.resource.Group {
+		return false
+	}
+	return name == n.resource.Resource || name == strings.ToLower(n.kind)
+}
+
+// isBuiltInGroup returns whether the API group is built into Kubernetes, whose resources have the short names above.
+// Custom resources must be in a group containing a dot, and the "k8s.io" domain is reserved for Kubernetes itself.
+func isBuiltInGroup(group string) bool {
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+// Focus restricts the graph to the objects of the resource with the given name, in any of the visualized namespaces,
+// along with the objects they lead to and the objects leading to them e.g. the ReplicaSets and Pods of a Deployment,
+// and the HorizontalPodAutoscaler scaling it, as well as the objects leading directly to any they lead to. The
+// resource is referenced as by kubectl e.g. "deploy".
+// Traffic allowed by NetworkPolicies is only drawn between the remaining objects, rather than followed, as it would
+// otherwise lead to most of the namespace.
+// It must be called once populated, and returns an error if no object matches.
+func (g *Grapher) Focus(resource, name string) error {
+	g.resolve()
+
+	focused := make(map[string]struct{})
+	for _, n := range g.nodes {
+		if !n.external && n.name == name && n.matches(resource) {
+			focused[n.id()] = struct{}{}
+		}
+	}
+	if len(focused) == 0 {
+		return fmt.Errorf("%s %q not found", resource, name)
+	}
+
+	// Follow the connections in each direction separately, as following them in both would lead to the whole graph.
+	follow := func(from func(c *connection) string, to func(c *connection) string) map[string]struct{} {
+		reached := make(map[string]struct{})
+		for id := range focused {
+			reached[id] = struct{}{}
+		}
+		changed := true
+		for changed {
+			changed = false
+			for _, c := range g.connections {
+				if c.traffic {
+					continue
+				}
+				if _, ok := reached[from(&c)]; !ok {
+					continue
+				}
+				if _, ok := reached[to(&c)]; !ok {
+					reached[to(&c)] = struct{}{}
+					changed = true
+				}
+			}
+		}
+		return reached
+	}
+	descendants := follow((*connection).sourceID, (*connection).destinationID)
+	kept := follow((*connection).destinationID, (*connection).sourceID)
+	for id := range descendants {
+		kept[id] = struct{}{}
+	}
+	// The objects leading directly to those the focused objects lead to are also related e.g. the ConfigMaps mounted
+	// by the Pods of a Deployment, and the Services selecting them.
+	for _, c := range g.connections {
+		if _, ok := descendants[c.destinationID()]; ok && !c.traffic {
+			kept[c.sourceID()] = struct{}{}
+		}
+	}
+
+	var nodes []node
+	for _, n := range g.nodes {
+		if _, ok := kept[n.id()]; ok {
+			nodes = append(nodes, n)
+		}
+	}
+	g.nodes = nodes
+
+	var connections []connection
+	for _, c := range g.connections {
+		_, sourceKept := kept[c.sourceID()]
+		_, destinationKept := kept[c.destinationID()]
+		if sourceKept && destinationKept {
+			connections = append(connections, c)
+		}
+	}
+	g.connections = connections
+	return nil
+}
@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatewayGroup is the API group of the Gateway API, whose types are read from unstructured objects as they are not
+// built into Kubernetes.
+const gatewayGroup = "gateway.networking.k8s.io"
+
+// isRoute returns whether an object is a Gateway API route e.g. an HTTPRoute or GRPCRoute.
+func isRoute(object *unstructured.Unstructured) bool {
+	return object.GroupVersionKind().Group == gatewayGroup && strings.HasSuffix(object.GetKind(), "Route")
+}
+
+// getNestedString returns the string field of an unstructured object, or the default value if it is absent.
+func getNestedString(object map[string]interface{}, field, defaultValue string) string {
+	value, ok, err := unstructured.NestedString(object, field)
+	if err != nil || !ok || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// populateGateway connects a Gateway to its GatewayClass.
+func (g *Grapher) populateGateway(object *unstructured.Unstructured) {
+	gatewayClassName, _, _ := unstructured.NestedString(object.Object, "spec", "gatewayClassName")
+	if gatewayClassName == "" {
+		return
+	}
+	g.connections = append(g.connections, connection{
+		sourceNamespace: object.GetNamespace(),
+		sourceName:      object.GetName(),
+		sourceKind:      object.GetKind(),
+		destinationName: gatewayClassName,
+		destinationKind: GatewayClass,
+	})
+}
+
+// populateRoute connects the Gateways a route attaches to to the route, labelled with the listener it attaches to,
+// and the route to the backends it routes to, labelled with their ports.
+// Gateways and Services may be referenced in other namespaces, and are represented by placeholders outside of the
+// namespaces until their own namespace is visualized.
+func (g *Grapher) populateRoute(object *unstructured.Unstructured) {
+	namespace := object.GetNamespace()
+	name := object.GetName()
+	kind := object.GetKind()
+
+	parentRefs, _, _ := unstructured.NestedSlice(object.Object, "spec", "parentRefs")
+	for _, p := range parentRefs {
+		parentRef, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parentNamespace := getNestedString(parentRef, "namespace", namespace)
+		parentName := getNestedString(parentRef, "name", "")
+		parentKind := getNestedString(parentRef, "kind", Gateway)
+		if parentNamespace != namespace && parentKind == Gateway {
+			g.nodes = append(g.nodes, newExternalNode(parentNamespace, parentName, parentKind, schema.GroupVersionResource{Group: gatewayGroup, Resource: "gateways"}))
+		}
+		g.connections = append(g.connections, connection{
+			label:                getNestedString(parentRef, "sectionName", ""),
+			sourceNamespace:      parentNamespace,
+			sourceName:           parentName,
+			sourceKind:           parentKind,
+			destinationNamespace: namespace,
+			destinationName:      name,
+			destinationKind:      kind,
+		})
+	}
+
+	// A backend may be referenced by many rules, on many ports. Only one connection is drawn between two nodes, so
+	// generate a single label for the connection listing each distinct port:
+	//     8080\n9090
+	var backends []endpoint
+	ports := make(map[endpoint][]string)
+	rules, _, _ := unstructured.NestedSlice(object.Object, "spec", "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		backendRefs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, b := range backendRefs {
+			backendRef, ok := b.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			backend := endpoint{
+				namespace: getNestedString(backendRef, "namespace", namespace),
+				name:      getNestedString(backendRef, "name", ""),
+				kind:      getNestedString(backendRef, "kind", Service),
+			}
+			if _, ok := ports[backend]; !ok {
+				backends = append(backends, backend)
+				ports[backend] = nil
+			}
+			// Ports are integers, but decoded as floats from manifests, so are formatted whatever their type.
+			port, ok, _ := unstructured.NestedFieldNoCopy(backendRef, "port")
+			if ok && !slices.Contains(ports[backend], fmt.Sprint(port)) {
+				ports[backend] = append(ports[backend], fmt.Sprint(port))
+			}
+		}
+	}
+	for _, backend := range backends {
+		if backend.namespace != namespace && backend.kind == Service {
+			g.nodes = append(g.nodes, newExternalNode(backend.namespace, backend.name, backend.kind, schema.GroupVersionResource{Version: "v1", Resource: "services"}))
+		}
+		g.connections = append(g.connections, connection{
+			label:                strings.Join(ports[backend], "\\n"),
+			sourceNamespace:      namespace,
+			sourceName:           name,
+			sourceKind:           kind,
+			destinationNamespace: backend.namespace,
+			destinationName:      backend.name,
+			destinationKind:      backend.kind,
+		})
+	}
+}
@@ -2,49 +2,185 @@ package graph
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/awalterschulze/gographviz"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/AyCarlito/kube-visualization/pkg/config"
 )
 
 const (
-	ConfigMap             string = "ConfigMap"
-	Endpoints             string = "Endpoints"
-	Ingress               string = "Ingress"
-	PersistentVolumeClaim string = "PersistentVolumeClaim"
-	Pod                   string = "Pod"
-	Secret                string = "Secret"
-	Service               string = "Service"
+	ClusterRole             string = "ClusterRole"
+	ConfigMap               string = "ConfigMap"
+	DaemonSet               string = "DaemonSet"
+	Deployment              string = "Deployment"
+	Endpoints               string = "Endpoints"
+	EndpointSlice           string = "EndpointSlice"
+	Gateway                 string = "Gateway"
+	GatewayClass            string = "GatewayClass"
+	HorizontalPodAutoscaler string = "HorizontalPodAutoscaler"
+	Ingress                 string = "Ingress"
+	IngressClass            string = "IngressClass"
+	NetworkPeer             string = "NetworkPeer"
+	NetworkPolicy           string = "NetworkPolicy"
+	Node                    string = "Node"
+	PersistentVolume        string = "PersistentVolume"
+	PersistentVolumeClaim   string = "PersistentVolumeClaim"
+	Pod                     string = "Pod"
+	PodDisruptionBudget     string = "PodDisruptionBudget"
+	PriorityClass           string = "PriorityClass"
+	ReplicaSet              string = "ReplicaSet"
+	Role                    string = "Role"
+	RoleBinding             string = "RoleBinding"
+	Secret                  string = "Secret"
+	Service                 string = "Service"
+	ServiceAccount          string = "ServiceAccount"
+	StatefulSet             string = "StatefulSet"
+	StorageClass            string = "StorageClass"
 )
 
+// ingressClassAnnotation is the deprecated annotation naming the IngressClass of an Ingress.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// defaultServiceAccount is the name of the ServiceAccount used by Pods which do not specify one.
+const defaultServiceAccount = "default"
+
+// OptFunc is a function that mutates a grapherOpts.
+type OptFunc func(*grapherOpts)
+
+// grapherOpts are the configuration options for the Grapher.
+type grapherOpts struct {
+	inferRanks bool
+	format     Format
+}
+
+// defaultOpts return the default configuration options for a Grapher.
+func defaultOpts() grapherOpts {
+	return grapherOpts{
+		inferRanks: false,
+		format:     Dot,
+	}
+}
+
+// WithInferredRanks returns an optFunc to mutate the inferRanks configuration option of the Grapher.
+// When set, the configured ranks are ignored and ranks are instead inferred from the connections between objects.
+func WithInferredRanks(infer bool) OptFunc {
+	return func(o *grapherOpts) {
+		o.inferRanks = infer
+	}
+}
+
+// WithFormat returns an optFunc to mutate the format configuration option of the Grapher.
+func WithFormat(f Format) OptFunc {
+	return func(o *grapherOpts) {
+		o.format = f
+	}
+}
+
+// IconResolver resolves the image representing a resource.
+type IconResolver interface {
+	// Icon returns the path to the image for a given resource e.g. "pods".
+	Icon(gr schema.GroupResource) string
+}
+
 // Grapher creates gographviz graphs.
 type Grapher struct {
-	connections    []connection
-	graph          *gographviz.Graph
-	assetsBasePath string
-	outputFilePath string
+	name       string
+	namespaces []string
+	// namespaceLabels are the labels of every namespace, keyed by name, or nil if they are not known.
+	namespaceLabels map[string]labels.Set
+	clusters        []cluster
+	ranks           []int
+	nodes           []node
+	connections     []connection
+	selections      []selection
+	resolved        bool
+	graph           *gographviz.Graph
+	icons           IconResolver
+	outputFilePath  string
+	opts            grapherOpts
 }
 
 // NewGrapher returns a new *Grapher.
-func NewGraph(a, o string) *Grapher {
-	return &Grapher{assetsBasePath: a, outputFilePath: o}
+// The IconResolver may be nil if the Grapher is never connected, as is the case when it is only populated for Diff.
+func NewGraph(i IconResolver, o string, opts ...OptFunc) *Grapher {
+	grapherOpts := defaultOpts()
+	for _, fn := range opts {
+		fn(&grapherOpts)
+	}
+	return &Grapher{icons: i, outputFilePath: o, opts: grapherOpts}
+}
+
+// node is a Kubernetes object to be represented in the graph.
+type node struct {
+	// cluster is empty unless several clusters are visualized.
+	cluster string
+	// namespace is empty for cluster-scoped objects.
+	namespace string
+	name      string
+	kind      string
+	rank      int
+	resource  config.Resource
+	object    *unstructured.Unstructured
+	// external is set for placeholders of objects referenced from outside the visualized namespaces, which have no
+	// object.
+	external bool
+	// isolated are the directions of traffic, if any, in which a Pod is isolated by NetworkPolicies.
+	isolated []string
+	state    state
+}
+
+// id returns the ID of the node in a gographviz.Graph.
+func (n *node) id() string {
+	return getSanitizedObjectName(getClusterQualifiedName(n.cluster, getQualifiedName(n.namespace, n.name)), n.kind)
+}
+
+// newExternalNode returns a placeholder node for an object outside of the visualized namespaces, identified by
+// namespace, name, kind and GVR.
+func newExternalNode(namespace, name, kind string, gvr schema.GroupVersionResource) node {
+	return node{namespace: namespace, name: name, kind: kind, resource: config.Resource{GroupVersionResource: gvr}, external: true}
 }
 
 // connection is a link between two Kubernetes objects.
+// The namespace of either object is empty if it is cluster-scoped. Both objects are always in the same cluster.
 type connection struct {
-	label           string
-	sourceName      string
-	sourceKind      string
-	destinationName string
-	destinationKind string
+	label                string
+	cluster              string
+	sourceNamespace      string
+	sourceName           string
+	sourceKind           string
+	destinationNamespace string
+	destinationName      string
+	destinationKind      string
+	// selected is set when the destination was matched by a label selector, rather than referenced by name.
+	selected bool
+	// traffic is set when the connection represents traffic allowed by NetworkPolicies.
+	traffic bool
+	state   state
+}
+
+// sourceID returns the ID of the source node of the connection in a gographviz.Graph.
+func (c *connection) sourceID() string {
+	return getSanitizedObjectName(getClusterQualifiedName(c.cluster, getQualifiedName(c.sourceNamespace, c.sourceName)), c.sourceKind)
+}
+
+// destinationID returns the ID of the destination node of the connection in a gographviz.Graph.
+func (c *connection) destinationID() string {
+	return getSanitizedObjectName(getClusterQualifiedName(c.cluster, getQualifiedName(c.destinationNamespace, c.destinationName)), c.destinationKind)
 }
 
 // sanitizedLabel returns the sanitized label of a connection.
@@ -53,19 +189,25 @@ func (c *connection) sanitizedLabel() string {
 	return fmt.Sprintf("\"%s\"", c.label)
 }
 
-// getSubgraphName returns the name of a subgraph in a gographviz.Graph.
-func getSubgraphName(i int) string {
-	return fmt.Sprintf("rank_%s", fmt.Sprintf("%04s", strconv.Itoa(i)))
+// getNamespaceSubgraphName returns the name of the subgraph of a namespace in a gographviz.Graph.
+// Graphviz draws a border around subgraphs whose name begins with "cluster".
+func getNamespaceSubgraphName(namespace string) string {
+	return getSanitizedObjectName(namespace, "cluster_namespace")
 }
 
-// getDummyNodeName returns the name of a dummy node for use in a gographviz.Graph.
-func getDummyNodeName(i int) string {
-	return fmt.Sprintf("node_%s", fmt.Sprintf("%04s", strconv.Itoa(i)))
+// getSubgraphName returns the name of a subgraph of a namespace in a gographviz.Graph.
+func getSubgraphName(namespace string, i int) string {
+	return fmt.Sprintf("\"rank_%s_%s\"", namespace, fmt.Sprintf("%04s", strconv.Itoa(i)))
+}
+
+// getDummyNodeName returns the name of a dummy node of a namespace for use in a gographviz.Graph.
+func getDummyNodeName(namespace string, i int) string {
+	return fmt.Sprintf("\"node_%s_%s\"", namespace, fmt.Sprintf("%04s", strconv.Itoa(i)))
 }
 
 // getImagePath returns the path to an image for a given resource.
-func (g *Grapher) getImagePath(resource string) string {
-	return fmt.Sprintf("\"%s\"", filepath.Join(g.assetsBasePath, resource+".png"))
+func (g *Grapher) getImagePath(gr schema.GroupResource) string {
+	return fmt.Sprintf("\"%s\"", g.icons.Icon(gr))
 }
 
 // getNodeLabel returns the label of a node in a gographviz.Graph.
@@ -75,20 +217,70 @@ func getNodeLabel(node string) string {
 	return fmt.Sprintf("\"\\n\\n\\n\\n\\n\\n\\n\\n\\n%s\"", node)
 }
 
+// getConditionLabel returns the label of an EndpointSlice condition, which is unknown when nil.
+func getConditionLabel(condition *bool) string {
+	if condition == nil {
+		return "unknown"
+	}
+	return strconv.FormatBool(*condition)
+}
+
+// getExternalNameService returns the namespace and name of the Service aliased by the external name of an
+// ExternalName Service in the namespace, and whether the external name refers to a Service at all.
+// The external name is qualified if it unambiguously refers to a Service e.g. "db.data.svc.cluster.local". Whereas
+// "db.data" may equally be an external host such as "example.com".
+func getExternalNameService(externalName, namespace string) (string, string, bool, bool) {
+	labels := strings.Split(strings.TrimSuffix(externalName, "."), ".")
+	switch {
+	case len(labels) == 1:
+		return namespace, labels[0], true, true
+	case len(labels) == 2:
+		return labels[1], labels[0], false, true
+	case labels[2] == "svc":
+		return labels[1], labels[0], true, true
+	}
+	return "", "", false, false
+}
+
+// getQualifiedName returns the name of an object qualified by its namespace e.g. "default/web", unless the object is
+// cluster-scoped.
+func getQualifiedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
 // getSanitizedObjectName returns the sanitized name of an object in a gographviz.Graph.
 // The provided name and kind are wrapped in double quotes.
 func getSanitizedObjectName(name, kind string) string {
 	return fmt.Sprintf("\"%s_%s\"", kind, name)
 }
 
-// Scaffold scaffolds the graph for later population.
-// The scaffold is composed of:
+// Scaffold prepares the graph for later population.
+// Any objects populated by a previous use of the Grapher are discarded, allowing it to be reused.
+// The graph itself is not built until Connect, at which point the scaffold is composed of:
 //   - Basic object metadata.
-//   - A single subgraph representing the namespace to be visualised.
-//   - A subgraph for each unique rank in the GVRs to be retrieved.
+//   - A subgraph representing each cluster to be visualised, if several clusters have been merged.
+//   - A subgraph representing each namespace to be visualised.
+//   - A subgraph within each namespace subgraph for each unique rank in the GVRs to be retrieved.
 //   - An invisble node in each rank subgraph.
 //   - Invisible edges connecting the invisble nodes across the rank subgraphs.
-func (g *Grapher) Scaffold(name, namespace string, ranks []int) {
+func (g *Grapher) Scaffold(name string, namespaces []string, ranks []int) {
+	g.name = name
+	g.namespaces = namespaces
+	g.namespaceLabels = nil
+	g.ranks = ranks
+	g.clusters = nil
+	g.nodes = nil
+	g.connections = nil
+	g.selections = nil
+	g.resolved = false
+}
+
+// scaffold builds the scaffold of the graph.
+func (g *Grapher) scaffold(ranks []int) *gographviz.Graph {
+	name := g.name
 	graph := gographviz.NewGraph()
 	// In a directed graph, the arrows between nodes have a direction.
 	// Direction indicates ownership, and reflects the owner references stored on the Kubernetes object.
@@ -97,69 +289,189 @@ func (g *Grapher) Scaffold(name, namespace string, ranks []int) {
 	// Setting the heirarchy here, Top to bottom.
 	graph.AddAttr(name, "rankdir", "TB")
 
-	// Highest level subgraph for the namespace.
-	graph.AddSubGraph(name, getSanitizedObjectName(namespace, "namespace"), map[string]string{
-		"style": "dotted",
-	})
-
-	graph.AddNode(getSanitizedObjectName(namespace, "namespace"), getSanitizedObjectName(namespace, "namespace"), map[string]string{
-		"penwidth": "0",
-		"height":   "0",
-		"width":    "0",
-		"margin":   "0",
-		"label":    getNodeLabel(namespace),
-		"image":    g.getImagePath("namespaces"),
-	})
-
-	// A subgraph within the namespace subgraph for each kind of resource.
-	for _, i := range ranks {
-		graph.AddSubGraph(getSanitizedObjectName(namespace, "namespace"), getSubgraphName(i), map[string]string{
-			"rank":  "same",
-			"style": "invis",
+	// Without clusters, the namespaces are at the top level of the graph.
+	if len(g.clusters) == 0 {
+		g.scaffoldNamespaces(graph, name, "", g.namespaces, ranks)
+		return graph
+	}
+	for _, c := range g.clusters {
+		graph.AddSubGraph(name, getClusterSubgraphName(c.name), map[string]string{
+			"label": fmt.Sprintf("\"%s\"", c.name),
 		})
+		g.scaffoldNamespaces(graph, getClusterSubgraphName(c.name), c.name, c.namespaces, ranks)
+	}
 
-		// A dummy node in subgraph.
-		graph.AddNode(getSubgraphName(i), getDummyNodeName(i), map[string]string{
-			"style":  "invis",
-			"height": "0",
-			"width":  "0",
-			"margin": "0",
+	return graph
+}
+
+// scaffoldNamespaces adds the scaffold of each namespace in a cluster to the parent graph.
+func (g *Grapher) scaffoldNamespaces(graph *gographviz.Graph, parent, cluster string, namespaces []string, ranks []int) {
+	for _, ns := range namespaces {
+		// Namespaces of the same name in different clusters are distinct.
+		namespace := getClusterQualifiedName(cluster, ns)
+
+		// Highest level subgraph for the namespace.
+		graph.AddSubGraph(parent, getNamespaceSubgraphName(namespace), map[string]string{
+			"style": "dotted",
 		})
-	}
 
-	// Each dummy node is connected with an invisible edge.
-	// Note the index here is offset by 1 as the final node cannot be the source node for a connection as there
-	// is no destination node to connect it to!
-	for i := 0; i < (len(ranks) - 1); i++ {
-		graph.AddEdge(getDummyNodeName(ranks[i]), getDummyNodeName(ranks[i+1]), true, map[string]string{"style": "invis"})
-	}
+		graph.AddNode(getNamespaceSubgraphName(namespace), getSanitizedObjectName(namespace, "namespace"), map[string]string{
+			"penwidth": "0",
+			"height":   "0",
+			"width":    "0",
+			"margin":   "0",
+			"label":    getNodeLabel(ns),
+			"image":    g.getImagePath(schema.GroupResource{Resource: "namespaces"}),
+		})
 
-	// Connect the namespace node to the first dummy node.
-	graph.AddEdge(getSanitizedObjectName(namespace, "namespace"), getDummyNodeName(ranks[0]), true, map[string]string{"style": "invis"})
+		// A subgraph within the namespace subgraph for each kind of resource.
+		for _, i := range ranks {
+			graph.AddSubGraph(getNamespaceSubgraphName(namespace), getSubgraphName(namespace, i), map[string]string{
+				"rank":  "same",
+				"style": "invis",
+			})
+
+			// A dummy node in subgraph.
+			graph.AddNode(getSubgraphName(namespace, i), getDummyNodeName(namespace, i), map[string]string{
+				"style":  "invis",
+				"height": "0",
+				"width":  "0",
+				"margin": "0",
+			})
+		}
+
+		// Each dummy node is connected with an invisible edge.
+		// Note the index here is offset by 1 as the final node cannot be the source node for a connection as there
+		// is no destination node to connect it to!
+		for i := 0; i < (len(ranks) - 1); i++ {
+			graph.AddEdge(getDummyNodeName(namespace, ranks[i]), getDummyNodeName(namespace, ranks[i+1]), true, map[string]string{"style": "invis"})
+		}
 
-	g.graph = graph
+		// Connect the namespace node to the first dummy node.
+		if len(ranks) > 0 {
+			graph.AddEdge(getSanitizedObjectName(namespace, "namespace"), getDummyNodeName(namespace, ranks[0]), true, map[string]string{"style": "invis"})
+		}
+	}
 }
 
-// Connect connects related nodes in the graph.
+// resolve completes the connections which cannot be determined until every object has been populated.
+// Objects are only resolved once e.g. those combined from other graphs by Diff or Merge, which resolve them first, or
+// those focused on by Focus. Resolving them again would duplicate the traffic allowed by NetworkPolicies, along with
+// their peers.
+func (g *Grapher) resolve() {
+	if g.resolved {
+		return
+	}
+	g.resolved = true
+	g.resolveScopes()
+	g.resolveNetworkPolicies()
+	g.resolveSelections()
+	g.labelRoleBindings()
+	g.pruneClusterScoped()
+}
+
+// Connect builds the graph from the populated objects, connecting related nodes.
 func (g *Grapher) Connect() {
+	g.resolve()
+	ranks := g.ranks
+	if g.opts.inferRanks {
+		ranks = g.inferRanks()
+	}
+	g.graph = g.scaffold(ranks)
+
+	// Add a node for each object to the subgraph corresponding to its rank.
+	for _, n := range g.nodes {
+		if n.external || !n.resource.IsNamespaced() {
+			continue
+		}
+		attrs := map[string]string{
+			"penwidth": "0",
+			"label":    getNodeLabel(n.name),
+			"image":    g.getImagePath(n.resource.GroupResource()),
+		}
+		if len(n.isolated) > 0 {
+			attrs["penwidth"] = "2"
+			attrs["color"] = isolationColor
+			attrs["fontcolor"] = isolationColor
+			attrs["label"] = getNodeLabel(fmt.Sprintf("%s\\nisolated: %s", n.name, strings.Join(n.isolated, ",")))
+		}
+		n.state.decorateNode(n.name, attrs)
+		g.graph.AddNode(getSubgraphName(getClusterQualifiedName(n.cluster, n.namespace), n.rank), n.id(), attrs)
+	}
+
+	// Add a node outside of the namespaces for each cluster-scoped object.
+	for _, n := range g.nodes {
+		if n.external || n.resource.IsNamespaced() {
+			continue
+		}
+		attrs := map[string]string{
+			"penwidth": "0",
+			"label":    getNodeLabel(n.name),
+			"image":    g.getImagePath(n.resource.GroupResource()),
+		}
+		n.state.decorateNode(n.name, attrs)
+		g.graph.AddNode(g.getParentGraphName(n.cluster), n.id(), attrs)
+	}
+
+	// Add a node outside of the namespaces for each object referenced from outside of them, unless it is also
+	// present as an object. The same object may be referenced many times, but is only represented once.
+	for _, n := range g.nodes {
+		if !n.external {
+			continue
+		}
+		if _, ok := g.graph.Nodes.Lookup[n.id()]; ok {
+			continue
+		}
+		attrs := map[string]string{
+			"penwidth":  "0",
+			"fontcolor": "gray40",
+			"label":     getNodeLabel(getQualifiedName(n.namespace, n.name)),
+			"image":     g.getImagePath(n.resource.GroupResource()),
+		}
+		// Placeholders which are not Kubernetes objects, such as the peers of a NetworkPolicy, are drawn as text.
+		if n.resource.Resource == "" {
+			attrs = map[string]string{
+				"shape":     "box",
+				"style":     "dashed",
+				"fontcolor": "gray40",
+				"label":     fmt.Sprintf("\"%s\"", n.name),
+			}
+		}
+		n.state.decorateNode(getQualifiedName(n.namespace, n.name), attrs)
+		g.graph.AddNode(g.getParentGraphName(n.cluster), n.id(), attrs)
+	}
+
 	// Now create the edges for any connections that have been tracked.
 	for _, connection := range g.connections {
-		sourceNodeName := getSanitizedObjectName(connection.sourceName, connection.sourceKind)
+		sourceNodeName := connection.sourceID()
 		// It's possible that the connection may be towards a resource that isn't part of this visualisation.
 		// Check for the existence of the source node first, and skip if it does not exist.
 		if _, ok := g.graph.Nodes.Lookup[sourceNodeName]; !ok {
 			continue
 		}
-		dstNodeName := getSanitizedObjectName(connection.destinationName, connection.destinationKind)
+		dstNodeName := connection.destinationID()
+		// Likewise for the destination node e.g. a Service whose Endpoints are absent from rendered manifests.
+		if _, ok := g.graph.Nodes.Lookup[dstNodeName]; !ok {
+			continue
+		}
 		// There may already be a connection between the source and destination node.
 		// We only want to represent one.
 		if _, ok := g.graph.Edges.SrcToDsts[sourceNodeName][dstNodeName]; ok {
 			continue
 		}
 		attrs := map[string]string{"style": "dashed"}
+		if connection.selected {
+			attrs["style"] = "dotted"
+		}
+		if connection.traffic {
+			attrs["style"] = "bold"
+			attrs["color"] = trafficColor
+			attrs["fontcolor"] = trafficColor
+		}
 		if connection.label != "" {
 			attrs["label"] = connection.sanitizedLabel()
 		}
+		connection.state.decorateEdge(attrs)
 		g.graph.AddEdge(sourceNodeName, dstNodeName, true, attrs)
 
 	}
@@ -167,15 +479,12 @@ func (g *Grapher) Connect() {
 
 // Populate populates the graph.
 func (g *Grapher) Populate(objects *unstructured.UnstructuredList, resource config.Resource) {
-	// Add a node for each object in the List to the subgraph corresponding to the resource's rank.
+	// Track a node for each object in the List, ranked by the resource's rank until Connect decides otherwise.
 	for _, object := range objects.Items {
 		name := object.GetName()
 		kind := object.GetKind()
-		g.graph.AddNode(getSubgraphName(resource.Rank), getSanitizedObjectName(name, kind), map[string]string{
-			"penwidth": "0",
-			"label":    getNodeLabel(name),
-			"image":    g.getImagePath(resource.Resource),
-		})
+		namespace := object.GetNamespace()
+		g.nodes = append(g.nodes, node{namespace: namespace, name: name, kind: kind, rank: resource.Rank, resource: resource, object: object.DeepCopy()})
 		// If the object contains a controlling owner reference, track it.
 		// We do this so an edge can be constructed to link the object node to the owner node.
 		// Ideally, we would skip the tracking and just create the edge now. But the owner node may not exist at
@@ -183,10 +492,12 @@ func (g *Grapher) Populate(objects *unstructured.UnstructuredList, resource conf
 		ownerReferences := object.GetOwnerReferences()
 		if len(ownerReferences) > 0 && ownerReferences[0].Controller != nil && *ownerReferences[0].Controller {
 			g.connections = append(g.connections, connection{
-				sourceName:      ownerReferences[0].Name,
-				sourceKind:      ownerReferences[0].Kind,
-				destinationName: name,
-				destinationKind: kind,
+				sourceNamespace:      namespace,
+				sourceName:           ownerReferences[0].Name,
+				sourceKind:           ownerReferences[0].Kind,
+				destinationNamespace: namespace,
+				destinationName:      name,
+				destinationKind:      kind,
 			})
 		}
 
@@ -204,12 +515,44 @@ func (g *Grapher) Populate(objects *unstructured.UnstructuredList, resource conf
 				connectionLabel += fmt.Sprintf("%d/%s/%s\\n", port.Port, port.Protocol, port.Name)
 			}
 			g.connections = append(g.connections, connection{
-				label:           connectionLabel,
-				sourceName:      name,
-				sourceKind:      kind,
-				destinationName: name,
-				destinationKind: Endpoints,
+				label:                connectionLabel,
+				sourceNamespace:      namespace,
+				sourceName:           name,
+				sourceKind:           kind,
+				destinationNamespace: namespace,
+				destinationName:      name,
+				destinationKind:      Endpoints,
 			})
+			// Services are also connected to the Pods matching their selector, as the Endpoints may be absent or
+			// stale. A Service without a selector has its Endpoints managed by some other means, and selects nothing.
+			if len(service.Spec.Selector) > 0 {
+				g.selections = append(g.selections, selection{
+					label:           connectionLabel,
+					sourceNamespace: namespace,
+					sourceName:      name,
+					sourceKind:      kind,
+					selector:        labels.SelectorFromSet(service.Spec.Selector),
+				})
+			}
+			// ExternalName Services are connected to the Service they alias, which may be in another namespace. A
+			// qualified Service in a namespace which is not visualized is represented by a placeholder.
+			if service.Spec.Type == corev1.ServiceTypeExternalName {
+				serviceNamespace, serviceName, qualified, ok := getExternalNameService(service.Spec.ExternalName, namespace)
+				if ok {
+					if qualified && serviceNamespace != namespace {
+						g.nodes = append(g.nodes, newExternalNode(serviceNamespace, serviceName, kind, resource.GroupVersionResource))
+					}
+					g.connections = append(g.connections, connection{
+						label:                "externalName",
+						sourceNamespace:      namespace,
+						sourceName:           name,
+						sourceKind:           kind,
+						destinationNamespace: serviceNamespace,
+						destinationName:      serviceName,
+						destinationKind:      kind,
+					})
+				}
+			}
 		}
 
 		// Endpoints are connected to the pods referenced in its subsets.
@@ -231,16 +574,70 @@ func (g *Grapher) Populate(objects *unstructured.UnstructuredList, resource conf
 						connectionLabel += fmt.Sprintf("%d/%s/%s\\n", port.Port, port.Protocol, port.Name)
 					}
 					g.connections = append(g.connections, connection{
-						label:           connectionLabel,
-						sourceName:      name,
-						sourceKind:      kind,
-						destinationName: address.TargetRef.Name,
-						destinationKind: Pod,
+						label:                connectionLabel,
+						sourceNamespace:      namespace,
+						sourceName:           name,
+						sourceKind:           kind,
+						destinationNamespace: namespace,
+						destinationName:      address.TargetRef.Name,
+						destinationKind:      Pod,
 					})
 				}
 			}
 		}
 
+		// EndpointSlices are connected to their Service by label, and to the Pods referenced by their endpoints.
+		if kind == EndpointSlice {
+			endpointSlice := &discoveryv1.EndpointSlice{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), endpointSlice)
+			if serviceName, ok := endpointSlice.Labels[discoveryv1.LabelServiceName]; ok {
+				g.connections = append(g.connections, connection{
+					sourceNamespace:      namespace,
+					sourceName:           serviceName,
+					sourceKind:           Service,
+					destinationNamespace: namespace,
+					destinationName:      name,
+					destinationKind:      kind,
+				})
+			}
+			// Generate the ports part of the label in the same way as for Endpoints:
+			//     8080/TCP/api\n3001/TCP/metrics
+			var portsLabel string
+			for _, port := range endpointSlice.Ports {
+				var number int32
+				if port.Port != nil {
+					number = *port.Port
+				}
+				var protocol corev1.Protocol
+				if port.Protocol != nil {
+					protocol = *port.Protocol
+				}
+				var portName string
+				if port.Name != nil {
+					portName = *port.Name
+				}
+				portsLabel += fmt.Sprintf("%d/%s/%s\\n", number, protocol, portName)
+			}
+			for _, endpoint := range endpointSlice.Endpoints {
+				if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != Pod {
+					continue
+				}
+				// Followed by the conditions of the endpoint:
+				//     ready/serving/terminating: true/true/false
+				conditions := endpoint.Conditions
+				g.connections = append(g.connections, connection{
+					label: portsLabel + fmt.Sprintf("ready/serving/terminating: %s/%s/%s", getConditionLabel(conditions.Ready),
+						getConditionLabel(conditions.Serving), getConditionLabel(conditions.Terminating)),
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      endpoint.TargetRef.Name,
+					destinationKind:      Pod,
+				})
+			}
+		}
+
 		// Ingresses are connected to the service defined in the IngressBackend.
 		if kind == Ingress {
 			ingress := &networkingv1.Ingress{}
@@ -259,39 +656,363 @@ func (g *Grapher) Populate(objects *unstructured.UnstructuredList, resource conf
 						continue
 					}
 					g.connections = append(g.connections, connection{
-						label:           path.Path,
-						sourceName:      name,
-						sourceKind:      kind,
-						destinationName: serviceName,
-						destinationKind: Service,
+						label:                path.Path,
+						sourceNamespace:      namespace,
+						sourceName:           name,
+						sourceKind:           kind,
+						destinationNamespace: namespace,
+						destinationName:      serviceName,
+						destinationKind:      Service,
 					})
 				}
 			}
+
+			// Ingresses are also connected to their IngressClass, falling back to the deprecated annotation.
+			ingressClassName := ingress.Annotations[ingressClassAnnotation]
+			if ingress.Spec.IngressClassName != nil {
+				ingressClassName = *ingress.Spec.IngressClassName
+			}
+			if ingressClassName != "" {
+				g.connections = append(g.connections, connection{
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      ingressClassName,
+					destinationKind:      IngressClass,
+				})
+			}
 		}
 
-		// Pods are connected to ConfigMaps, Secrets and PersistentVolumeClaims through their volumes.
+		// Pods are connected to the ConfigMaps, Secrets and PersistentVolumeClaims they reference.
 		if kind == Pod {
 			pod := &corev1.Pod{}
 			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), pod)
-			for _, volume := range pod.Spec.Volumes {
-				var volumeName, volumeKind string
-				if volume.ConfigMap != nil {
-					volumeName = volume.ConfigMap.Name
-					volumeKind = ConfigMap
-				} else if volume.Secret != nil {
-					volumeName = volume.Secret.SecretName
-					volumeKind = Secret
-				} else if volume.PersistentVolumeClaim != nil {
-					volumeName = volume.PersistentVolumeClaim.ClaimName
-					volumeKind = PersistentVolumeClaim
-				} else {
-					continue
+			// An object may be referenced many times, through many mechanisms. Only one connection is drawn between
+			// two nodes, so generate a single label for the connection listing each distinct mechanism:
+			//     volume/config\nenv/DB_HOST/host
+			var referenced []reference
+			mechanisms := make(map[reference][]string)
+			for _, ref := range getPodReferences(pod) {
+				key := reference{name: ref.name, kind: ref.kind}
+				if _, ok := mechanisms[key]; !ok {
+					referenced = append(referenced, key)
+				}
+				if !slices.Contains(mechanisms[key], ref.mechanism) {
+					mechanisms[key] = append(mechanisms[key], ref.mechanism)
+				}
+			}
+			for _, ref := range referenced {
+				g.connections = append(g.connections, connection{
+					label:                strings.Join(mechanisms[ref], "\\n"),
+					sourceNamespace:      namespace,
+					sourceName:           ref.name,
+					sourceKind:           ref.kind,
+					destinationNamespace: namespace,
+					destinationName:      name,
+					destinationKind:      kind,
+				})
+			}
+
+			// Pods are connected to the Node they are scheduled to, and the PriorityClass they are scheduled with.
+			if pod.Spec.NodeName != "" {
+				g.connections = append(g.connections, connection{
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      pod.Spec.NodeName,
+					destinationKind:      Node,
+				})
+			}
+			if pod.Spec.PriorityClassName != "" {
+				var connectionLabel string
+				if pod.Spec.Priority != nil {
+					connectionLabel = fmt.Sprintf("priority: %d", *pod.Spec.Priority)
+				}
+				g.connections = append(g.connections, connection{
+					label:                connectionLabel,
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      pod.Spec.PriorityClassName,
+					destinationKind:      PriorityClass,
+				})
+			}
+
+			// Pods are connected to the ServiceAccount they run as.
+			serviceAccountName := pod.Spec.ServiceAccountName
+			if serviceAccountName == "" {
+				serviceAccountName = defaultServiceAccount
+			}
+			g.connections = append(g.connections, connection{
+				sourceNamespace:      namespace,
+				sourceName:           serviceAccountName,
+				sourceKind:           ServiceAccount,
+				destinationNamespace: namespace,
+				destinationName:      name,
+				destinationKind:      kind,
+			})
+		}
+
+		// ServiceAccounts are connected to the Secrets they reference, for both API tokens and pulling images.
+		if kind == ServiceAccount {
+			serviceAccount := &corev1.ServiceAccount{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), serviceAccount)
+			for _, secret := range serviceAccount.Secrets {
+				g.connections = append(g.connections, connection{
+					label:                "token",
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      secret.Name,
+					destinationKind:      Secret,
+				})
+			}
+			for _, secret := range serviceAccount.ImagePullSecrets {
+				g.connections = append(g.connections, connection{
+					label:                "imagePullSecret",
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      secret.Name,
+					destinationKind:      Secret,
+				})
+			}
+		}
+
+		// HorizontalPodAutoscalers are connected to the resource they scale.
+		if kind == HorizontalPodAutoscaler {
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), hpa)
+			// The minimum number of replicas defaults to 1 when unset.
+			minReplicas := int32(1)
+			if hpa.Spec.MinReplicas != nil {
+				minReplicas = *hpa.Spec.MinReplicas
+			}
+			// Consider an HPA scaling between 2 and 10 replicas, currently at 3 replicas and wanting 5.
+			// Generate a label for the connection:
+			//     min/max: 2/10\ncurrent/desired: 3/5
+			g.connections = append(g.connections, connection{
+				label: fmt.Sprintf("min/max: %d/%d\\ncurrent/desired: %d/%d", minReplicas, hpa.Spec.MaxReplicas,
+					hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas),
+				sourceNamespace:      namespace,
+				sourceName:           name,
+				sourceKind:           kind,
+				destinationNamespace: namespace,
+				destinationName:      hpa.Spec.ScaleTargetRef.Name,
+				destinationKind:      hpa.Spec.ScaleTargetRef.Kind,
+			})
+		}
+
+		// PodDisruptionBudgets are connected to the Pods matching their selector.
+		if kind == PodDisruptionBudget {
+			pdb := &policyv1.PodDisruptionBudget{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), pdb)
+			// A nil selector matches no Pods, whereas an empty selector matches every Pod.
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil {
+				continue
+			}
+			// Generate a label for the connection from whichever of the two mutually exclusive fields is set e.g.
+			//     minAvailable: 1
+			var connectionLabel string
+			if pdb.Spec.MinAvailable != nil {
+				connectionLabel = fmt.Sprintf("minAvailable: %s", pdb.Spec.MinAvailable.String())
+			} else if pdb.Spec.MaxUnavailable != nil {
+				connectionLabel = fmt.Sprintf("maxUnavailable: %s", pdb.Spec.MaxUnavailable.String())
+			}
+			g.selections = append(g.selections, selection{
+				label:           connectionLabel,
+				sourceNamespace: namespace,
+				sourceName:      name,
+				sourceKind:      kind,
+				selector:        selector,
+			})
+		}
+
+		// Workloads are connected to the Pods matching their selector. Their Pods are usually reached through
+		// ownership instead, so these only add the connections missing where it is absent e.g. from manifests, or
+		// from Pods adopted by a workload other than the one controlling them.
+		// A workload without a valid selector selects nothing, but is otherwise connected as usual e.g. a StatefulSet to
+		// its PersistentVolumeClaims.
+		if selector, ok := getWorkloadSelector(&object); ok {
+			g.selections = append(g.selections, selection{
+				sourceNamespace: namespace,
+				sourceName:      name,
+				sourceKind:      kind,
+				selector:        selector,
+			})
+		}
+
+		// PersistentVolumeClaims are connected to the PersistentVolume bound to them or, until bound, the
+		// StorageClass from which a PersistentVolume will be provisioned.
+		if kind == PersistentVolumeClaim {
+			claim := &corev1.PersistentVolumeClaim{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), claim)
+			connectionLabel := getStorageLabel(getClaimCapacity(claim), claim.Spec.AccessModes, string(claim.Status.Phase))
+			if claim.Spec.VolumeName != "" {
+				g.connections = append(g.connections, connection{
+					label:                connectionLabel,
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      claim.Spec.VolumeName,
+					destinationKind:      PersistentVolume,
+				})
+			} else if claim.Spec.StorageClassName != nil && *claim.Spec.StorageClassName != "" {
+				g.connections = append(g.connections, connection{
+					label:                connectionLabel,
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      *claim.Spec.StorageClassName,
+					destinationKind:      StorageClass,
+				})
+			}
+		}
+
+		// PersistentVolumes are connected to their StorageClass.
+		if kind == PersistentVolume {
+			volume := &corev1.PersistentVolume{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), volume)
+			if volume.Spec.StorageClassName != "" {
+				g.connections = append(g.connections, connection{
+					label:                getStorageLabel(volume.Spec.Capacity, volume.Spec.AccessModes, string(volume.Status.Phase)),
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: namespace,
+					destinationName:      volume.Spec.StorageClassName,
+					destinationKind:      StorageClass,
+				})
+			}
+		}
+
+		// StatefulSets are connected to the PersistentVolumeClaims created from their volume claim templates.
+		if kind == StatefulSet {
+			statefulSet := &appsv1.StatefulSet{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), statefulSet)
+			// The number of replicas defaults to 1, and ordinals to start from 0, when unset.
+			replicas, start := int32(1), int32(0)
+			if statefulSet.Spec.Replicas != nil {
+				replicas = *statefulSet.Spec.Replicas
+			}
+			if statefulSet.Spec.Ordinals != nil {
+				start = statefulSet.Spec.Ordinals.Start
+			}
+			for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+				for _, claimName := range getClaimNames(template.Name, name, start, replicas) {
+					g.connections = append(g.connections, connection{
+						label:                fmt.Sprintf("volumeClaimTemplate/%s", template.Name),
+						sourceNamespace:      namespace,
+						sourceName:           name,
+						sourceKind:           kind,
+						destinationNamespace: namespace,
+						destinationName:      claimName,
+						destinationKind:      PersistentVolumeClaim,
+					})
+				}
+			}
+		}
+
+		// RoleBindings are connected to the Role or ClusterRole they bind, and to each of their subjects.
+		// ClusterRoles, and subjects outside of the namespaces, are represented by placeholders outside of them.
+		if kind == RoleBinding {
+			roleBinding := &rbacv1.RoleBinding{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), roleBinding)
+			roleNamespace := namespace
+			if roleBinding.RoleRef.Kind == ClusterRole {
+				roleNamespace = ""
+				g.nodes = append(g.nodes, newExternalNode(roleNamespace, roleBinding.RoleRef.Name, ClusterRole, rbacv1.SchemeGroupVersion.WithResource("clusterroles")))
+			}
+			g.connections = append(g.connections, connection{
+				sourceNamespace:      namespace,
+				sourceName:           name,
+				sourceKind:           kind,
+				destinationNamespace: roleNamespace,
+				destinationName:      roleBinding.RoleRef.Name,
+				destinationKind:      roleBinding.RoleRef.Kind,
+			})
+			for _, subject := range roleBinding.Subjects {
+				var subjectNamespace string
+				switch subject.Kind {
+				case rbacv1.ServiceAccountKind:
+					// The namespace of a ServiceAccount subject defaults to that of the RoleBinding.
+					subjectNamespace = namespace
+					if subject.Namespace != "" && subject.Namespace != namespace {
+						subjectNamespace = subject.Namespace
+						g.nodes = append(g.nodes, newExternalNode(subjectNamespace, subject.Name, subject.Kind, corev1.SchemeGroupVersion.WithResource("serviceaccounts")))
+					}
+				case rbacv1.UserKind:
+					g.nodes = append(g.nodes, newExternalNode(subjectNamespace, subject.Name, subject.Kind, rbacv1.SchemeGroupVersion.WithResource("users")))
+				case rbacv1.GroupKind:
+					g.nodes = append(g.nodes, newExternalNode(subjectNamespace, subject.Name, subject.Kind, rbacv1.SchemeGroupVersion.WithResource("groups")))
 				}
 				g.connections = append(g.connections, connection{
-					sourceName:      volumeName,
-					sourceKind:      volumeKind,
-					destinationName: name,
-					destinationKind: kind,
+					sourceNamespace:      namespace,
+					sourceName:           name,
+					sourceKind:           kind,
+					destinationNamespace: subjectNamespace,
+					destinationName:      subject.Name,
+					destinationKind:      subject.Kind,
+				})
+			}
+		}
+
+		// NetworkPolicies are connected to the Pods matching their pod selector, labelled with the directions of
+		// traffic they apply to. The traffic they allow is connected once every Pod has been populated.
+		if kind == NetworkPolicy {
+			policy := &networkingv1.NetworkPolicy{}
+			err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), policy)
+			if err != nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+			if err != nil {
+				continue
+			}
+			var policyTypes []string
+			for _, policyType := range getPolicyTypes(policy) {
+				policyTypes = append(policyTypes, string(policyType))
+			}
+			g.selections = append(g.selections, selection{
+				label:           strings.Join(policyTypes, ","),
+				sourceNamespace: namespace,
+				sourceName:      name,
+				sourceKind:      kind,
+				selector:        selector,
+			})
+		}
+
+		// Gateways are connected to their GatewayClass, and routes to the Gateways and Services they reference.
+		if kind == Gateway && object.GroupVersionKind().Group == gatewayGroup {
+			g.populateGateway(&object)
+		}
+		if isRoute(&object) {
+			g.populateRoute(&object)
+		}
+
+		// Token Secrets are connected to the ServiceAccount named in their annotations.
+		// Since Kubernetes 1.24, these are no longer listed in the secrets of the ServiceAccount.
+		if kind == Secret {
+			secret := &corev1.Secret{}
+			runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), secret)
+			serviceAccountName := secret.Annotations[corev1.ServiceAccountNameKey]
+			if secret.Type == corev1.SecretTypeServiceAccountToken && serviceAccountName != "" {
+				g.connections = append(g.connections, connection{
+					label:                "token",
+					sourceNamespace:      namespace,
+					sourceName:           serviceAccountName,
+					sourceKind:           ServiceAccount,
+					destinationNamespace: namespace,
+					destinationName:      name,
+					destinationKind:      kind,
 				})
 			}
 		}
@@ -299,17 +1020,30 @@ func (g *Grapher) Populate(objects *unstructured.UnstructuredList, resource conf
 	}
 }
 
-// WriteDotFile writes the string representation of the graph to file.
-func (g *Grapher) WriteDotFile() error {
-	file, err := os.Create(g.outputFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to create dot file: %v", err)
+// String returns the string representation of the graph.
+func (g *Grapher) String() string {
+	return g.graph.String()
+}
+
+// Objects returns the object represented by each node in the graph, keyed by the ID of the node.
+func (g *Grapher) Objects() map[string]*unstructured.Unstructured {
+	objects := make(map[string]*unstructured.Unstructured)
+	for _, n := range g.nodes {
+		if n.external {
+			continue
+		}
+		objects[strings.Trim(n.id(), "\"")] = n.object
 	}
-	defer file.Close()
+	return objects
+}
 
-	_, err = file.WriteString(g.graph.String())
+// WriteDotFile writes the string representation of the graph to file, or to stdout if the output file is Stdout.
+// The file is replaced atomically, so that a reader never observes a partially written graph when the same file is
+// written repeatedly.
+func (g *Grapher) WriteDotFile() error {
+	err := writeOutput(g.outputFilePath, []byte(g.graph.String()))
 	if err != nil {
-		return fmt.Errorf("failed to write to dot file: %v", err)
+		return fmt.Errorf("failed to write dot file: %v", err)
 	}
 	return nil
 }
@@ -0,0 +1,486 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPopulate(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests string
+		// want are the connections populated from the manifests once selectors are resolved and RoleBindings
+		// labelled, as "source -> destination: label". Connections to cluster-scoped objects are in the namespace of
+		// the referring object until scopes are resolved.
+		want []string
+	}{
+		{
+			name: "pod runs as the default service account",
+			manifests: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+`,
+			want: []string{"ServiceAccount_shop/default -> Pod_shop/web: "},
+		},
+		{
+			name: "pod runs as its service account",
+			manifests: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+spec:
+  serviceAccountName: web
+`,
+			want: []string{"ServiceAccount_shop/web -> Pod_shop/web: "},
+		},
+		{
+			name: "service account is connected to its secrets",
+			manifests: `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  namespace: shop
+secrets:
+- name: web-token
+imagePullSecrets:
+- name: registry
+`,
+			want: []string{
+				"ServiceAccount_shop/web -> Secret_shop/web-token: token",
+				"ServiceAccount_shop/web -> Secret_shop/registry: imagePullSecret",
+			},
+		},
+		{
+			name: "token secret is connected to the service account in its annotations",
+			manifests: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-token
+  namespace: shop
+  annotations:
+    kubernetes.io/service-account.name: web
+type: kubernetes.io/service-account-token
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: other
+  namespace: shop
+  annotations:
+    kubernetes.io/service-account.name: web
+type: Opaque
+`,
+			want: []string{"ServiceAccount_shop/web -> Secret_shop/web-token: token"},
+		},
+		{
+			name: "horizontal pod autoscaler is connected to its scale target",
+			manifests: `
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+  namespace: shop
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+  maxReplicas: 5
+status:
+  currentReplicas: 2
+  desiredReplicas: 3
+`,
+			want: []string{`HorizontalPodAutoscaler_shop/web -> Deployment_shop/web: min/max: 1/5\ncurrent/desired: 2/3`},
+		},
+		{
+			name: "pod disruption budget is connected to the pods it selects",
+			manifests: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: shop
+  labels:
+    app: db
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: shop
+spec:
+  maxUnavailable: 50%
+  selector:
+    matchLabels:
+      app: web
+`,
+			want: []string{
+				"ServiceAccount_shop/default -> Pod_shop/web: ",
+				"ServiceAccount_shop/default -> Pod_shop/db: ",
+				"PodDisruptionBudget_shop/web -> Pod_shop/web: maxUnavailable: 50%",
+			},
+		},
+		{
+			name: "endpoint slice is connected to its service and the pods of its endpoints",
+			manifests: `
+apiVersion: discovery.k8s.io/v1
+kind: EndpointSlice
+metadata:
+  name: web-abc12
+  namespace: shop
+  labels:
+    kubernetes.io/service-name: web
+addressType: IPv4
+ports:
+- name: api
+  port: 8080
+  protocol: TCP
+endpoints:
+- addresses: [10.0.0.1]
+  conditions:
+    ready: true
+    serving: true
+    terminating: false
+  targetRef:
+    kind: Pod
+    name: web-1
+- addresses: [10.0.0.2]
+  conditions:
+    ready: false
+  targetRef:
+    kind: Pod
+    name: web-2
+- addresses: [10.0.0.3]
+`,
+			want: []string{
+				"Service_shop/web -> EndpointSlice_shop/web-abc12: ",
+				`EndpointSlice_shop/web-abc12 -> Pod_shop/web-1: 8080/TCP/api\nready/serving/terminating: true/true/false`,
+				`EndpointSlice_shop/web-abc12 -> Pod_shop/web-2: 8080/TCP/api\nready/serving/terminating: false/unknown/unknown`,
+			},
+		},
+		{
+			name: "pod is connected once to each object referenced through its environment",
+			manifests: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+spec:
+  serviceAccountName: web
+  initContainers:
+  - name: migrate
+    envFrom:
+    - secretRef:
+        name: db
+  containers:
+  - name: web
+    env:
+    - name: DB_HOST
+      valueFrom:
+        configMapKeyRef:
+          name: config
+          key: host
+    - name: DB_PASSWORD
+      valueFrom:
+        secretKeyRef:
+          name: db
+          key: password
+    - name: PLAIN
+      value: plain
+    envFrom:
+    - prefix: APP_
+      configMapRef:
+        name: config
+    - configMapRef:
+        name: config
+  ephemeralContainers:
+  - name: debug
+    envFrom:
+    - configMapRef:
+        name: debug
+`,
+			want: []string{
+				`ConfigMap_shop/config -> Pod_shop/web: env/DB_HOST/host\nenvFrom/APP_\nenvFrom`,
+				`Secret_shop/db -> Pod_shop/web: env/DB_PASSWORD/password\nenvFrom`,
+				"ConfigMap_shop/debug -> Pod_shop/web: envFrom",
+				"ServiceAccount_shop/web -> Pod_shop/web: ",
+			},
+		},
+		{
+			name: "role binding is connected to its role and subjects",
+			manifests: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+  namespace: shop
+rules:
+- apiGroups: [""]
+  resources: [pods, configmaps]
+  resourceNames: [app]
+  verbs: [get, list]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+  namespace: shop
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: reader
+subjects:
+- kind: ServiceAccount
+  name: web
+- kind: ServiceAccount
+  name: monitor
+  namespace: monitoring
+- kind: User
+  name: jane
+  apiGroup: rbac.authorization.k8s.io
+- kind: Group
+  name: developers
+  apiGroup: rbac.authorization.k8s.io
+`,
+			want: []string{
+				"RoleBinding_shop/reader -> Role_shop/reader: get,list pods[app],configmaps[app]",
+				"RoleBinding_shop/reader -> ServiceAccount_shop/web: ",
+				"RoleBinding_shop/reader -> ServiceAccount_monitoring/monitor: ",
+				"RoleBinding_shop/reader -> User_jane: ",
+				"RoleBinding_shop/reader -> Group_developers: ",
+			},
+		},
+		{
+			name: "role binding is connected to the cluster role it binds",
+			manifests: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: view
+  namespace: shop
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+`,
+			want: []string{"RoleBinding_shop/view -> ClusterRole_view: "},
+		},
+		{
+			name: "statefulset without a selector is connected to its volume claims",
+			manifests: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: shop
+spec:
+  replicas: 2
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+`,
+			want: []string{
+				"StatefulSet_shop/db -> PersistentVolumeClaim_shop/data-db-0: volumeClaimTemplate/data",
+				"StatefulSet_shop/db -> PersistentVolumeClaim_shop/data-db-1: volumeClaimTemplate/data",
+			},
+		},
+		{
+			name: "statefulset volume claims start from the first ordinal",
+			manifests: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: db
+  ordinals:
+    start: 3
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+`,
+			want: []string{"StatefulSet_shop/db -> PersistentVolumeClaim_shop/data-db-3: volumeClaimTemplate/data"},
+		},
+		{
+			name: "bound volume claim is connected to its volume",
+			manifests: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: shop
+spec:
+  storageClassName: standard
+  volumeName: pv-1
+  accessModes: [ReadWriteOnce]
+status:
+  phase: Bound
+  capacity:
+    storage: 10Gi
+`,
+			want: []string{"PersistentVolumeClaim_shop/data -> PersistentVolume_shop/pv-1: 10Gi/RWO/Bound"},
+		},
+		{
+			name: "pending volume claim is connected to its storage class",
+			manifests: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: shop
+spec:
+  storageClassName: standard
+  accessModes: [ReadWriteOnce, ReadWriteMany]
+  resources:
+    requests:
+      storage: 5Gi
+status:
+  phase: Pending
+`,
+			want: []string{"PersistentVolumeClaim_shop/data -> StorageClass_shop/standard: 5Gi/RWO,RWX/Pending"},
+		},
+		{
+			name: "volume is connected to its storage class",
+			manifests: `
+apiVersion: v1
+kind: PersistentVolume
+metadata:
+  name: pv-1
+spec:
+  storageClassName: standard
+  capacity:
+    storage: 10Gi
+  accessModes: [ReadOnlyMany]
+status:
+  phase: Available
+`,
+			want: []string{"PersistentVolume_pv-1 -> StorageClass_standard: 10Gi/ROX/Available"},
+		},
+		{
+			name: "ingress is connected to its ingress class",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: shop
+spec:
+  ingressClassName: nginx
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: legacy
+  namespace: shop
+  annotations:
+    kubernetes.io/ingress.class: traefik
+`,
+			want: []string{
+				"Ingress_shop/web -> IngressClass_shop/nginx: ",
+				"Ingress_shop/legacy -> IngressClass_shop/traefik: ",
+			},
+		},
+		{
+			name: "pod is connected to its node and priority class",
+			manifests: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+spec:
+  nodeName: node-1
+  priorityClassName: high
+  priority: 1000
+`,
+			want: []string{
+				"Pod_shop/web -> Node_shop/node-1: ",
+				"Pod_shop/web -> PriorityClass_shop/high: priority: 1000",
+				"ServiceAccount_shop/default -> Pod_shop/web: ",
+			},
+		},
+		{
+			name: "gateway is connected to its gateway class",
+			manifests: `
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: public
+  namespace: shop
+spec:
+  gatewayClassName: istio
+`,
+			want: []string{"Gateway_shop/public -> GatewayClass_istio: "},
+		},
+		{
+			name: "route is connected to its gateways and backends",
+			manifests: `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: web
+  namespace: shop
+spec:
+  parentRefs:
+  - name: public
+    sectionName: https
+  - name: internal
+    namespace: infra
+  rules:
+  - backendRefs:
+    - name: web
+      port: 8080
+    - name: api
+      namespace: backend
+      port: 9090
+  - backendRefs:
+    - name: web
+      port: 8081
+    - name: web
+      port: 8080
+`,
+			want: []string{
+				"Gateway_shop/public -> HTTPRoute_shop/web: https",
+				"Gateway_infra/internal -> HTTPRoute_shop/web: ",
+				`HTTPRoute_shop/web -> Service_shop/web: 8080\n8081`,
+				"HTTPRoute_shop/web -> Service_backend/api: 9090",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPopulatedGrapher(t, []string{"shop"}, tt.manifests)
+			g.resolveSelections()
+			g.labelRoleBindings()
+
+			var got []string
+			for _, c := range g.connections {
+				got = append(got, fmt.Sprintf("%s -> %s: %s", strings.Trim(c.sourceID(), `"`), strings.Trim(c.destinationID(), `"`), c.label))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Populate() connections = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
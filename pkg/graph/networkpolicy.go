@@ -0,0 +1,297 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// trafficColor is the color of connections representing traffic allowed by NetworkPolicies.
+	trafficColor = "blue"
+	// isolationColor is the color of Pods isolated by NetworkPolicies, without any traffic allowed.
+	isolationColor = "purple"
+)
+
+// endpoint is the source or destination of traffic, either a Pod or a peer outside of the namespaces.
+type endpoint struct {
+	namespace string
+	name      string
+	kind      string
+}
+
+// flow is traffic allowed from a source to a destination.
+type flow struct {
+	source      endpoint
+	destination endpoint
+}
+
+// resolveNetworkPolicies connects Pods, and peers outside of the namespaces, by the traffic allowed between them by
+// NetworkPolicies. Pods are isolated in a direction once selected by a NetworkPolicy for that direction, and are
+// marked as isolated if no traffic is allowed in that direction i.e. they are subject to a default deny.
+func (g *Grapher) resolveNetworkPolicies() {
+	var pods []int
+	for i, n := range g.nodes {
+		if n.kind == Pod && n.object != nil {
+			pods = append(pods, i)
+		}
+	}
+
+	var flows []flow
+	ports := make(map[flow][]string)
+	allow := func(source, destination endpoint, label string) {
+		if source == destination {
+			return
+		}
+		f := flow{source: source, destination: destination}
+		if _, ok := ports[f]; !ok {
+			flows = append(flows, f)
+		}
+		if !slices.Contains(ports[f], label) {
+			ports[f] = append(ports[f], label)
+		}
+	}
+	isolated := make(map[int][]networkingv1.PolicyType)
+	allowed := make(map[int][]networkingv1.PolicyType)
+
+	for _, n := range g.nodes {
+		if n.kind != NetworkPolicy || n.object == nil {
+			continue
+		}
+		policy := &networkingv1.NetworkPolicy{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(n.object.UnstructuredContent(), policy)
+		if err != nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil {
+			continue
+		}
+		// A NetworkPolicy only applies to Pods in its own namespace.
+		var selected []int
+		for _, i := range pods {
+			if g.nodes[i].namespace == n.namespace && selector.Matches(labels.Set(g.nodes[i].object.GetLabels())) {
+				selected = append(selected, i)
+			}
+		}
+
+		policyTypes := getPolicyTypes(policy)
+		for _, policyType := range policyTypes {
+			for _, i := range selected {
+				isolated[i] = append(isolated[i], policyType)
+			}
+		}
+		// Rules only apply in the directions the NetworkPolicy applies to e.g. egress rules of a NetworkPolicy with
+		// only the Ingress policy type are ignored.
+		if slices.Contains(policyTypes, networkingv1.PolicyTypeIngress) {
+			for _, rule := range policy.Spec.Ingress {
+				label := getPortsLabel(rule.Ports)
+				for _, peer := range g.getPeers(n.namespace, rule.From, pods) {
+					for _, i := range selected {
+						allow(peer, endpoint{namespace: n.namespace, name: g.nodes[i].name, kind: Pod}, label)
+						allowed[i] = append(allowed[i], networkingv1.PolicyTypeIngress)
+					}
+				}
+			}
+		}
+		if slices.Contains(policyTypes, networkingv1.PolicyTypeEgress) {
+			for _, rule := range policy.Spec.Egress {
+				label := getPortsLabel(rule.Ports)
+				for _, peer := range g.getPeers(n.namespace, rule.To, pods) {
+					for _, i := range selected {
+						allow(endpoint{namespace: n.namespace, name: g.nodes[i].name, kind: Pod}, peer, label)
+						allowed[i] = append(allowed[i], networkingv1.PolicyTypeEgress)
+					}
+				}
+			}
+		}
+	}
+
+	for _, f := range flows {
+		g.connections = append(g.connections, connection{
+			label:                strings.Join(ports[f], "\\n"),
+			sourceNamespace:      f.source.namespace,
+			sourceName:           f.source.name,
+			sourceKind:           f.source.kind,
+			destinationNamespace: f.destination.namespace,
+			destinationName:      f.destination.name,
+			destinationKind:      f.destination.kind,
+			traffic:              true,
+		})
+	}
+	for i, policyTypes := range isolated {
+		for _, policyType := range []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress} {
+			if slices.Contains(policyTypes, policyType) && !slices.Contains(allowed[i], policyType) {
+				g.nodes[i].isolated = append(g.nodes[i].isolated, strings.ToLower(string(policyType)))
+			}
+		}
+	}
+}
+
+// getPolicyTypes returns the directions of traffic a NetworkPolicy applies to.
+// When unspecified, a NetworkPolicy always applies to ingress, and applies to egress if it has any egress rules.
+func getPolicyTypes(policy *networkingv1.NetworkPolicy) []networkingv1.PolicyType {
+	if len(policy.Spec.PolicyTypes) > 0 {
+		return policy.Spec.PolicyTypes
+	}
+	policyTypes := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if len(policy.Spec.Egress) > 0 {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeEgress)
+	}
+	return policyTypes
+}
+
+// LabelNamespaces records the labels of the Namespace objects, against which the namespace selectors of NetworkPolicy
+// peers are matched. It must be called once scaffolded, with every Namespace known e.g. those in the cluster.
+// Until called, the namespaces outside of those visualized are unknown, so any of them may match a namespace selector.
+func (g *Grapher) LabelNamespaces(objects *unstructured.UnstructuredList) {
+	g.namespaceLabels = make(map[string]labels.Set)
+	for _, object := range objects.Items {
+		namespaceLabels := labels.Set{corev1.LabelMetadataName: object.GetName()}
+		for key, value := range object.GetLabels() {
+			namespaceLabels[key] = value
+		}
+		g.namespaceLabels[object.GetName()] = namespaceLabels
+	}
+}
+
+// getNamespaceLabels returns the labels of a namespace. The labels of a namespace without a Namespace object e.g. in
+// manifests, are only its name, as labelled by the API server.
+func (g *Grapher) getNamespaceLabels(namespace string) labels.Set {
+	if namespaceLabels, ok := g.namespaceLabels[namespace]; ok {
+		return namespaceLabels
+	}
+	return labels.Set{corev1.LabelMetadataName: namespace}
+}
+
+// selectsOtherNamespaces returns whether a namespace selector may select namespaces other than those visualized.
+func (g *Grapher) selectsOtherNamespaces(selector labels.Selector) bool {
+	if g.namespaceLabels == nil {
+		return true
+	}
+	for namespace, namespaceLabels := range g.namespaceLabels {
+		if !slices.Contains(g.namespaces, namespace) && selector.Matches(namespaceLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPeers returns the Pods, and peers outside of the namespaces, matched by the peers of a NetworkPolicy rule in the
+// namespace. A pod selector alone selects Pods in the namespace of the NetworkPolicy, whereas a namespace selector
+// selects Pods in the visualized namespaces whose labels it matches, and in any other namespaces it may match.
+// Every other peer, including the other namespaces, is represented by a placeholder outside of the namespaces, named
+// after the peer e.g. "10.0.0.0/8".
+func (g *Grapher) getPeers(namespace string, peers []networkingv1.NetworkPolicyPeer, pods []int) []endpoint {
+	// A rule without peers allows traffic to and from anywhere.
+	if len(peers) == 0 {
+		return []endpoint{g.addPeer("any")}
+	}
+
+	var endpoints []endpoint
+	for _, peer := range peers {
+		switch {
+		case peer.IPBlock != nil:
+			name := peer.IPBlock.CIDR
+			if len(peer.IPBlock.Except) > 0 {
+				name = fmt.Sprintf("%s except %s", name, strings.Join(peer.IPBlock.Except, ","))
+			}
+			endpoints = append(endpoints, g.addPeer(name))
+		case peer.NamespaceSelector != nil:
+			namespaceSelector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+			if err != nil {
+				continue
+			}
+			// Without a pod selector, every Pod in the selected namespaces is selected.
+			podSelector := labels.Everything()
+			if peer.PodSelector != nil {
+				podSelector, err = metav1.LabelSelectorAsSelector(peer.PodSelector)
+				if err != nil {
+					continue
+				}
+			}
+			for _, i := range pods {
+				n := g.nodes[i]
+				if namespaceSelector.Matches(g.getNamespaceLabels(n.namespace)) && podSelector.Matches(labels.Set(n.object.GetLabels())) {
+					endpoints = append(endpoints, endpoint{namespace: n.namespace, name: n.name, kind: Pod})
+				}
+			}
+			if !g.selectsOtherNamespaces(namespaceSelector) {
+				continue
+			}
+			name := fmt.Sprintf("namespaces: %s", getSelectorLabel(peer.NamespaceSelector))
+			if peer.PodSelector != nil {
+				name = fmt.Sprintf("%s\\npods: %s", name, getSelectorLabel(peer.PodSelector))
+			}
+			endpoints = append(endpoints, g.addPeer(name))
+		case peer.PodSelector != nil:
+			selector, err := metav1.LabelSelectorAsSelector(peer.PodSelector)
+			if err != nil {
+				continue
+			}
+			for _, i := range pods {
+				if g.nodes[i].namespace == namespace && selector.Matches(labels.Set(g.nodes[i].object.GetLabels())) {
+					endpoints = append(endpoints, endpoint{namespace: namespace, name: g.nodes[i].name, kind: Pod})
+				}
+			}
+		}
+	}
+	return endpoints
+}
+
+// addPeer tracks a placeholder for a peer outside of the namespaces, unless already tracked by another rule,
+// returning an endpoint for it.
+// Peers are not Kubernetes objects, and so have no resource.
+func (g *Grapher) addPeer(name string) endpoint {
+	peer := node{name: name, kind: NetworkPeer, external: true}
+	if !slices.ContainsFunc(g.nodes, func(n node) bool { return n.id() == peer.id() }) {
+		g.nodes = append(g.nodes, peer)
+	}
+	return endpoint{name: name, kind: NetworkPeer}
+}
+
+// getSelectorLabel returns the label of a label selector, which is "all" if it matches everything.
+func getSelectorLabel(selector *metav1.LabelSelector) string {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return "all"
+	}
+	return metav1.FormatLabelSelector(selector)
+}
+
+// getPortsLabel returns the label of the ports of a NetworkPolicy rule, in the form port/protocol, one per line.
+// Consider two ports:
+//   - &NetworkPolicyPort{Protocol:TCP,Port:8080,EndPort:nil,}
+//   - &NetworkPolicyPort{Protocol:nil,Port:nil,EndPort:nil,}
+//
+// Generate a label:
+//
+//	8080/TCP\nany/TCP
+func getPortsLabel(ports []networkingv1.NetworkPolicyPort) string {
+	// A rule without ports allows traffic on every port.
+	if len(ports) == 0 {
+		return "any"
+	}
+	var lines []string
+	for _, port := range ports {
+		protocol := "TCP"
+		if port.Protocol != nil {
+			protocol = string(*port.Protocol)
+		}
+		number := "any"
+		if port.Port != nil {
+			number = port.Port.String()
+			if port.EndPort != nil {
+				number = fmt.Sprintf("%s-%d", number, *port.EndPort)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s/%s", number, protocol))
+	}
+	return strings.Join(lines, "\\n")
+}
@@ -0,0 +1,263 @@
+package graph
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestResolveNetworkPolicies(t *testing.T) {
+	pods := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: shop
+  labels:
+    app: db
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: other
+  labels:
+    app: web
+---
+`
+	tests := []struct {
+		name      string
+		manifests string
+		// namespaceLabels are the labels of every Namespace object, or nil if they are unknown.
+		namespaceLabels map[string]map[string]string
+		wantFlows       []string
+		wantIsolated    map[string][]string
+	}{
+		{
+			name: "default deny isolates every pod in its namespace",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny
+  namespace: shop
+spec:
+  podSelector: {}
+  policyTypes: [Ingress, Egress]
+`,
+			wantIsolated: map[string][]string{
+				"Pod_shop/web": {"ingress", "egress"},
+				"Pod_shop/db":  {"ingress", "egress"},
+			},
+		},
+		{
+			name: "ingress from pods in the same namespace",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  ingress:
+  - from:
+    - podSelector:
+        matchLabels:
+          app: web
+    ports:
+    - port: 5432
+    - protocol: UDP
+      port: 53
+      endPort: 54
+`,
+			wantFlows: []string{`Pod_shop/web -> Pod_shop/db: 5432/TCP\n53-54/UDP`},
+		},
+		{
+			name: "rule without peers allows traffic from anywhere",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  ingress:
+  - {}
+`,
+			wantFlows: []string{"NetworkPeer_any -> Pod_shop/db: any"},
+		},
+		{
+			name: "egress rules apply when policy types are unspecified",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  egress:
+  - to:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+        except: [10.1.0.0/16]
+`,
+			wantFlows:    []string{"Pod_shop/db -> NetworkPeer_10.0.0.0/8 except 10.1.0.0/16: any"},
+			wantIsolated: map[string][]string{"Pod_shop/db": {"ingress"}},
+		},
+		{
+			name: "egress rules are ignored without the egress policy type",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  policyTypes: [Ingress]
+  egress:
+  - to:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+`,
+			wantIsolated: map[string][]string{"Pod_shop/db": {"ingress"}},
+		},
+		{
+			name: "namespace selector may match unknown namespaces",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: other
+`,
+			wantFlows: []string{
+				"Pod_other/web -> Pod_shop/db: any",
+				`NetworkPeer_namespaces: kubernetes.io/metadata.name=other -> Pod_shop/db: any`,
+			},
+		},
+		{
+			name: "namespace selector matching only visualized namespaces",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          team: a
+      podSelector:
+        matchLabels:
+          app: web
+`,
+			namespaceLabels: map[string]map[string]string{
+				"shop":  {"team": "a"},
+				"other": {"team": "a"},
+				"z":     {"team": "b"},
+			},
+			wantFlows: []string{
+				"Pod_shop/web -> Pod_shop/db: any",
+				"Pod_other/web -> Pod_shop/db: any",
+			},
+		},
+		{
+			name: "namespace selector matching other namespaces",
+			manifests: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          team: b
+`,
+			namespaceLabels: map[string]map[string]string{
+				"shop":  {"team": "a"},
+				"other": {"team": "a"},
+				"z":     {"team": "b"},
+			},
+			wantFlows: []string{"NetworkPeer_namespaces: team=b -> Pod_shop/db: any"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPopulatedGrapher(t, []string{"shop", "other"}, pods+tt.manifests)
+			if tt.namespaceLabels != nil {
+				namespaces := &unstructured.UnstructuredList{}
+				for _, name := range slices.Sorted(maps.Keys(tt.namespaceLabels)) {
+					namespace := unstructured.Unstructured{}
+					namespace.SetName(name)
+					namespace.SetLabels(tt.namespaceLabels[name])
+					namespaces.Items = append(namespaces.Items, namespace)
+				}
+				g.LabelNamespaces(namespaces)
+			}
+			g.resolveNetworkPolicies()
+
+			var flows []string
+			for _, c := range g.connections {
+				if c.traffic {
+					flows = append(flows, fmt.Sprintf("%s -> %s: %s", strings.Trim(c.sourceID(), `"`), strings.Trim(c.destinationID(), `"`), c.label))
+				}
+			}
+			if !slices.Equal(flows, tt.wantFlows) {
+				t.Errorf("resolveNetworkPolicies() flows = %q, want %q", flows, tt.wantFlows)
+			}
+			isolated := make(map[string][]string)
+			for _, n := range g.nodes {
+				if len(n.isolated) > 0 {
+					isolated[strings.Trim(n.id(), `"`)] = n.isolated
+				}
+			}
+			if !maps.EqualFunc(isolated, tt.wantIsolated, slices.Equal) {
+				t.Errorf("resolveNetworkPolicies() isolated = %v, want %v", isolated, tt.wantIsolated)
+			}
+		})
+	}
+}
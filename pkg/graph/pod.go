@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reference is an object referenced by a Pod, along with the mechanism through which it is referenced.
+type reference struct {
+	name      string
+	kind      string
+	mechanism string
+}
+
+// getPodReferences returns the ConfigMaps, Secrets and PersistentVolumeClaims referenced by a Pod, grouped by
+// mechanism in a stable order. Objects are referenced through:
+//   - Volumes, including projected volumes and the secret of CSI volumes e.g. "volume/config".
+//   - Image pull secrets e.g. "imagePullSecret".
+//   - The environment of every container, init container and ephemeral container e.g. "env/DB_HOST/host" for a
+//     single key, or "envFrom" for every key.
+func getPodReferences(pod *corev1.Pod) []reference {
+	var references []reference
+	for _, volume := range pod.Spec.Volumes {
+		mechanism := fmt.Sprintf("volume/%s", volume.Name)
+		switch {
+		case volume.ConfigMap != nil:
+			references = append(references, reference{volume.ConfigMap.Name, ConfigMap, mechanism})
+		case volume.Secret != nil:
+			references = append(references, reference{volume.Secret.SecretName, Secret, mechanism})
+		case volume.PersistentVolumeClaim != nil:
+			references = append(references, reference{volume.PersistentVolumeClaim.ClaimName, PersistentVolumeClaim, mechanism})
+		case volume.Projected != nil:
+			mechanism = fmt.Sprintf("projected/%s", volume.Name)
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					references = append(references, reference{source.ConfigMap.Name, ConfigMap, mechanism})
+				} else if source.Secret != nil {
+					references = append(references, reference{source.Secret.Name, Secret, mechanism})
+				}
+			}
+		case volume.CSI != nil && volume.CSI.NodePublishSecretRef != nil:
+			mechanism = fmt.Sprintf("csi/%s", volume.Name)
+			references = append(references, reference{volume.CSI.NodePublishSecretRef.Name, Secret, mechanism})
+		}
+	}
+
+	for _, secret := range pod.Spec.ImagePullSecrets {
+		references = append(references, reference{secret.Name, Secret, "imagePullSecret"})
+	}
+
+	// Ephemeral containers share the environment fields of regular containers, but are a distinct type.
+	var envs [][]corev1.EnvVar
+	var envFroms [][]corev1.EnvFromSource
+	for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		envs = append(envs, container.Env)
+		envFroms = append(envFroms, container.EnvFrom)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		envs = append(envs, container.Env)
+		envFroms = append(envFroms, container.EnvFrom)
+	}
+	for _, env := range envs {
+		for _, variable := range env {
+			if variable.ValueFrom == nil {
+				continue
+			}
+			if ref := variable.ValueFrom.ConfigMapKeyRef; ref != nil {
+				references = append(references, reference{ref.Name, ConfigMap, fmt.Sprintf("env/%s/%s", variable.Name, ref.Key)})
+			} else if ref := variable.ValueFrom.SecretKeyRef; ref != nil {
+				references = append(references, reference{ref.Name, Secret, fmt.Sprintf("env/%s/%s", variable.Name, ref.Key)})
+			}
+		}
+	}
+	for _, envFrom := range envFroms {
+		for _, source := range envFrom {
+			mechanism := "envFrom"
+			if source.Prefix != "" {
+				mechanism = fmt.Sprintf("envFrom/%s", source.Prefix)
+			}
+			if source.ConfigMapRef != nil {
+				references = append(references, reference{source.ConfigMapRef.Name, ConfigMap, mechanism})
+			} else if source.SecretRef != nil {
+				references = append(references, reference{source.SecretRef.Name, Secret, mechanism})
+			}
+		}
+	}
+
+	return references
+}
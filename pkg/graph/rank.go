@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"sort"
+)
+
+// rankSpacing is the distance between consecutive inferred ranks.
+// It mirrors the spacing conventionally used in the configuration file.
+const rankSpacing = 10
+
+// inferRanks ranks each node by the layer of its kind in a topological ordering of the connections between kinds,
+// returning the sorted unique ranks.
+// A kind is placed one layer beneath the deepest kind connected to it, so owners appear above the objects they own
+// and references appear above the objects referring to them. Kinds without connections are placed in the first
+// layer. Cycles are broken by placing the kind with the fewest unplaced predecessors, preferring the smaller
+// configured rank and then the name of the kind.
+func (g *Grapher) inferRanks() []int {
+	configuredRanks := make(map[string]int)
+	existing := make(map[string]string)
+	for _, n := range g.nodes {
+		// Placeholders have no configured rank, so only rank a kind by them in the absence of its objects.
+		if _, ok := configuredRanks[n.kind]; !ok || !n.external {
+			configuredRanks[n.kind] = n.resource.Rank
+		}
+		existing[n.id()] = n.kind
+	}
+
+	// Edges between kinds, only considering connections between nodes that will be drawn.
+	successors := make(map[string]map[string]struct{})
+	predecessors := make(map[string]map[string]struct{})
+	for kind := range configuredRanks {
+		successors[kind] = make(map[string]struct{})
+		predecessors[kind] = make(map[string]struct{})
+	}
+	for _, c := range g.connections {
+		sourceKind, ok := existing[c.sourceID()]
+		if !ok {
+			continue
+		}
+		destinationKind, ok := existing[c.destinationID()]
+		if !ok || sourceKind == destinationKind {
+			continue
+		}
+		successors[sourceKind][destinationKind] = struct{}{}
+		predecessors[destinationKind][sourceKind] = struct{}{}
+	}
+
+	// Kahn's algorithm, assigning each kind the longest path to it from a kind without predecessors.
+	layers := make(map[string]int)
+	unplaced := make(map[string]int)
+	for kind, p := range predecessors {
+		unplaced[kind] = len(p)
+	}
+	for len(unplaced) > 0 {
+		candidates := []string{}
+		for kind := range unplaced {
+			candidates = append(candidates, kind)
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if unplaced[a] != unplaced[b] {
+				return unplaced[a] < unplaced[b]
+			}
+			if configuredRanks[a] != configuredRanks[b] {
+				return configuredRanks[a] < configuredRanks[b]
+			}
+			return a < b
+		})
+		// Either the kind has no unplaced predecessors, or it is the best candidate for breaking a cycle.
+		kind := candidates[0]
+		layer := 0
+		for predecessor := range predecessors[kind] {
+			if l, ok := layers[predecessor]; ok {
+				layer = max(layer, l+1)
+			}
+		}
+		layers[kind] = layer
+		delete(unplaced, kind)
+		for successor := range successors[kind] {
+			if _, ok := unplaced[successor]; ok {
+				unplaced[successor]--
+			}
+		}
+	}
+
+	uniqueRanks := make(map[int]struct{})
+	for i := range g.nodes {
+		g.nodes[i].rank = (layers[g.nodes[i].kind] + 1) * rankSpacing
+		uniqueRanks[g.nodes[i].rank] = struct{}{}
+	}
+	ranks := []int{}
+	for rank := range uniqueRanks {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	return ranks
+}
@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+)
+
+func TestInferRanks(t *testing.T) {
+	tests := []struct {
+		name string
+		// kinds are the configured ranks of the kinds, each with a single object of the same name.
+		kinds map[string]int
+		// connections are the connections between the objects, from the kind of the source to that of the
+		// destination.
+		connections [][2]string
+		wantRanks   map[string]int
+		want        []int
+	}{
+		{
+			name:        "owners are ranked above the objects they own",
+			kinds:       map[string]int{Pod: 10, ReplicaSet: 20, Deployment: 30},
+			connections: [][2]string{{Deployment, ReplicaSet}, {ReplicaSet, Pod}},
+			wantRanks:   map[string]int{Deployment: 10, ReplicaSet: 20, Pod: 30},
+			want:        []int{10, 20, 30},
+		},
+		{
+			name:        "kind is ranked beneath the deepest kind connected to it",
+			kinds:       map[string]int{Service: 10, Endpoints: 20, Pod: 30},
+			connections: [][2]string{{Service, Pod}, {Service, Endpoints}, {Endpoints, Pod}},
+			wantRanks:   map[string]int{Service: 10, Endpoints: 20, Pod: 30},
+			want:        []int{10, 20, 30},
+		},
+		{
+			name:      "kinds without connections are ranked first",
+			kinds:     map[string]int{ConfigMap: 30, Secret: 40},
+			wantRanks: map[string]int{ConfigMap: 10, Secret: 10},
+			want:      []int{10},
+		},
+		{
+			name:        "connections between objects of the same kind are ignored",
+			kinds:       map[string]int{Pod: 30},
+			connections: [][2]string{{Pod, Pod}},
+			wantRanks:   map[string]int{Pod: 10},
+			want:        []int{10},
+		},
+		{
+			name:        "connections to objects which are not drawn are ignored",
+			kinds:       map[string]int{Pod: 30},
+			connections: [][2]string{{Service, Pod}},
+			wantRanks:   map[string]int{Pod: 10},
+			want:        []int{10},
+		},
+		{
+			name:        "cycle is broken by the configured rank",
+			kinds:       map[string]int{RoleBinding: 10, Role: 20},
+			connections: [][2]string{{RoleBinding, Role}, {Role, RoleBinding}},
+			wantRanks:   map[string]int{RoleBinding: 10, Role: 20},
+			want:        []int{10, 20},
+		},
+		{
+			name:        "cycle is broken by the name of the kind",
+			kinds:       map[string]int{RoleBinding: 10, Role: 10},
+			connections: [][2]string{{RoleBinding, Role}, {Role, RoleBinding}},
+			wantRanks:   map[string]int{Role: 10, RoleBinding: 20},
+			want:        []int{10, 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGraph(nil, "", WithInferredRanks(true))
+			for _, kind := range slices.Sorted(maps.Keys(tt.kinds)) {
+				g.nodes = append(g.nodes, node{namespace: "shop", name: "x", kind: kind, resource: config.Resource{Rank: tt.kinds[kind]}})
+			}
+			for _, c := range tt.connections {
+				g.connections = append(g.connections, connection{
+					sourceNamespace:      "shop",
+					sourceName:           "x",
+					sourceKind:           c[0],
+					destinationNamespace: "shop",
+					destinationName:      "x",
+					destinationKind:      c[1],
+				})
+			}
+
+			got := g.inferRanks()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("inferRanks() = %v, want %v", got, tt.want)
+			}
+			ranks := make(map[string]int)
+			for _, n := range g.nodes {
+				ranks[n.kind] = n.rank
+			}
+			if !maps.Equal(ranks, tt.wantRanks) {
+				t.Errorf("inferRanks() node ranks = %v, want %v", ranks, tt.wantRanks)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// labelRoleBindings labels the connection from each RoleBinding to the Role or ClusterRole it binds with a summary
+// of the rules of the role, if present. The role may be populated after the RoleBinding, so this is deferred until
+// every object has been populated.
+func (g *Grapher) labelRoleBindings() {
+	rules := make(map[string][]rbacv1.PolicyRule)
+	for _, n := range g.nodes {
+		if n.object == nil || (n.kind != Role && n.kind != ClusterRole) {
+			continue
+		}
+		// Roles and ClusterRoles share the same rules.
+		role := &rbacv1.Role{}
+		runtime.DefaultUnstructuredConverter.FromUnstructured(n.object.UnstructuredContent(), role)
+		rules[n.id()] = role.Rules
+	}
+
+	for i, c := range g.connections {
+		if c.sourceKind != RoleBinding || (c.destinationKind != Role && c.destinationKind != ClusterRole) {
+			continue
+		}
+		if r, ok := rules[c.destinationID()]; ok {
+			g.connections[i].label = getRulesLabel(r)
+		}
+	}
+}
+
+// getRulesLabel returns a label summarising the rules of a role, one rule per line.
+// Resources restricted to specific names are suffixed with the name in square brackets e.g. "configmaps[app]".
+// Consider two rules:
+//   - &PolicyRule{Verbs:[get list],APIGroups:[""],Resources:[pods pods/log],}
+//   - &PolicyRule{Verbs:[get],NonResourceURLs:[/healthz],}
+//
+// Generate a label:
+//
+//	get,list pods,pods/log\nget /healthz
+func getRulesLabel(rules []rbacv1.PolicyRule) string {
+	var lines []string
+	for _, rule := range rules {
+		targets := rule.Resources
+		if len(rule.ResourceNames) > 0 {
+			targets = nil
+			for _, resource := range rule.Resources {
+				for _, resourceName := range rule.ResourceNames {
+					targets = append(targets, fmt.Sprintf("%s[%s]", resource, resourceName))
+				}
+			}
+		}
+		targets = append(targets, rule.NonResourceURLs...)
+		lines = append(lines, fmt.Sprintf("%s %s", strings.Join(rule.Verbs, ","), strings.Join(targets, ",")))
+	}
+	return strings.Join(lines, "\\n")
+}
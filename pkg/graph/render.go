@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Format is a format in which the graph may be written.
+type Format string
+
+const (
+	Dot Format = "dot"
+	SVG Format = "svg"
+	PNG Format = "png"
+	PDF Format = "pdf"
+)
+
+// Stdout is the output file path which writes the graph to stdout instead of a file.
+const Stdout = "-"
+
+// dotBinary is the name of the Graphviz binary used to render formats other than Dot.
+const dotBinary = "dot"
+
+// renderers are the Graphviz output formats used to render each Format.
+// Cairo is preferred for PNG, as its antialiasing produces much clearer icons and labels.
+var renderers = map[Format]string{
+	SVG: "svg",
+	PNG: "png:cairo",
+	PDF: "pdf",
+}
+
+// extensions are the file extensions accepted for each Format.
+var extensions = map[Format][]string{
+	Dot: {".dot", ".gv"},
+	SVG: {".svg"},
+	PNG: {".png"},
+	PDF: {".pdf"},
+}
+
+// formats are the supported Formats, in the order in which they are listed.
+var formats = []Format{Dot, SVG, PNG, PDF}
+
+// Extension returns the preferred file extension of the format e.g. ".png", or an empty string if unsupported.
+func (f Format) Extension() string {
+	exts, ok := extensions[f]
+	if !ok {
+		return ""
+	}
+	return exts[0]
+}
+
+// ResolveFormat returns the Format in which to write the output file at path.
+// When format is empty, it is inferred from the file extension, which must be that of a supported Format.
+// Otherwise, the file extension must agree with the format, unless writing to Stdout, which defaults to Dot.
+func ResolveFormat(format, path string) (Format, error) {
+	extension := strings.ToLower(filepath.Ext(path))
+	if format == "" {
+		if path == Stdout {
+			return Dot, nil
+		}
+		for _, f := range formats {
+			if slices.Contains(extensions[f], extension) {
+				return f, nil
+			}
+		}
+		var supported []string
+		for _, f := range formats {
+			supported = append(supported, extensions[f]...)
+		}
+		return "", fmt.Errorf("unrecognised output file extension %q: expected one of %s, or set --format", extension, strings.Join(supported, ", "))
+	}
+
+	exts, ok := extensions[Format(format)]
+	if !ok {
+		return "", fmt.Errorf("unsupported format %q: must be one of dot, svg, png or pdf", format)
+	}
+	if path == Stdout || slices.Contains(exts, extension) {
+		return Format(format), nil
+	}
+	return "", fmt.Errorf("output file extension %q does not match format %q: expected one of %s", extension, format, strings.Join(exts, ", "))
+}
+
+// Write writes the graph to file in the configured format.
+// Formats other than Dot are rendered by the Graphviz dot binary, which must be installed.
+func (g *Grapher) Write() error {
+	if g.opts.format == Dot {
+		return g.WriteDotFile()
+	}
+
+	content, err := Render(g.graph.String(), g.opts.format)
+	if err != nil {
+		return err
+	}
+	return writeOutput(g.outputFilePath, content)
+}
+
+// Render returns the string representation of a graph rendered in a format other than Dot by the Graphviz dot binary,
+// which must be installed.
+func Render(dot string, format Format) ([]byte, error) {
+	renderer, ok := renderers[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	path, err := exec.LookPath(dotBinary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find graphviz %q binary, install graphviz or use the dot format: %v", dotBinary, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, "-T"+renderer)
+	cmd.Stdin = strings.NewReader(dot)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %v: %s", format, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeOutput writes content to Stdout, or otherwise replaces the file at path with it.
+func writeOutput(path string, content []byte) error {
+	if path != Stdout {
+		return writeFileAtomically(path, content)
+	}
+	_, err := os.Stdout.Write(content)
+	if err != nil {
+		return fmt.Errorf("failed to write to stdout: %v", err)
+	}
+	return nil
+}
+
+// writeFileAtomically replaces the file at path with content atomically, so that a reader never observes a
+// partially written file when the same file is written repeatedly.
+func writeFileAtomically(path string, content []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// Temporary files are only readable by their owner, unlike a file created in its place.
+	err = file.Chmod(0o644)
+	if err != nil {
+		return fmt.Errorf("failed to set file permissions: %v", err)
+	}
+	_, err = file.Write(content)
+	if err != nil {
+		return fmt.Errorf("failed to write to file: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close file: %v", err)
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to replace file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package graph
+
+import "testing"
+
+func TestResolveFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		path    string
+		want    Format
+		wantErr bool
+	}{
+		{
+			name: "inferred from extension",
+			path: "out.png",
+			want: PNG,
+		},
+		{
+			name: "inferred from alternative extension",
+			path: "out.gv",
+			want: Dot,
+		},
+		{
+			name: "inferred from upper case extension",
+			path: "out.SVG",
+			want: SVG,
+		},
+		{
+			name: "stdout defaults to dot",
+			path: Stdout,
+			want: Dot,
+		},
+		{
+			name:    "unrecognised extension",
+			path:    "out.jpg",
+			wantErr: true,
+		},
+		{
+			name:    "no extension",
+			path:    "out",
+			wantErr: true,
+		},
+		{
+			name:   "format agrees with extension",
+			format: "pdf",
+			path:   "out.pdf",
+			want:   PDF,
+		},
+		{
+			name:   "format written to stdout",
+			format: "svg",
+			path:   Stdout,
+			want:   SVG,
+		},
+		{
+			name:    "format disagrees with extension",
+			format:  "png",
+			path:    "out.svg",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			format:  "jpg",
+			path:    "out.jpg",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveFormat(tt.format, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatExtension(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{format: Dot, want: ".dot"},
+		{format: SVG, want: ".svg"},
+		{format: PNG, want: ".png"},
+		{format: PDF, want: ".pdf"},
+		{format: Format("jpg"), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got := tt.format.Extension()
+			if got != tt.want {
+				t.Errorf("Extension() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
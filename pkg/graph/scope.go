@@ -0,0 +1,88 @@
+package graph
+
+// resolveScopes moves the endpoints of connections to cluster-scoped objects out of the namespace of the object
+// referring to them. Connections are populated in the namespace of the referring object, as the scope of the object
+// referred to, such as the owner of an object, is not always known in advance.
+func (g *Grapher) resolveScopes() {
+	existing := make(map[string]struct{})
+	for _, n := range g.nodes {
+		existing[n.id()] = struct{}{}
+	}
+	clusterScoped := func(namespace, name, kind string) bool {
+		if namespace == "" {
+			return false
+		}
+		if _, ok := existing[getSanitizedObjectName(getQualifiedName(namespace, name), kind)]; ok {
+			return false
+		}
+		_, ok := existing[getSanitizedObjectName(name, kind)]
+		return ok
+	}
+	for i, c := range g.connections {
+		if clusterScoped(c.sourceNamespace, c.sourceName, c.sourceKind) {
+			g.connections[i].sourceNamespace = ""
+		}
+		if clusterScoped(c.destinationNamespace, c.destinationName, c.destinationKind) {
+			g.connections[i].destinationNamespace = ""
+		}
+	}
+}
+
+// pruneClusterScoped removes the cluster-scoped objects which are not referenced from the namespaces, so that only
+// those relevant to the namespaces are visualized e.g. the PersistentVolumes bound to its PersistentVolumeClaims,
+// rather than every PersistentVolume in the cluster.
+// A cluster-scoped object is referenced if it is connected to an object in a namespace in either direction, or is
+// connected to from another referenced cluster-scoped object e.g. the StorageClass of a referenced PersistentVolume.
+func (g *Grapher) pruneClusterScoped() {
+	namespaced := make(map[string]struct{})
+	clusterScoped := make(map[string]struct{})
+	for _, n := range g.nodes {
+		id := n.id()
+		if n.external || n.resource.IsNamespaced() {
+			namespaced[id] = struct{}{}
+		} else {
+			clusterScoped[id] = struct{}{}
+		}
+	}
+
+	referenced := make(map[string]struct{})
+	changed := true
+	reference := func(id string) {
+		if _, ok := clusterScoped[id]; !ok {
+			return
+		}
+		if _, ok := referenced[id]; !ok {
+			referenced[id] = struct{}{}
+			changed = true
+		}
+	}
+	// Referenced cluster-scoped objects may reference others in turn, so repeat until nothing changes.
+	for changed {
+		changed = false
+		for _, c := range g.connections {
+			sourceNodeName := c.sourceID()
+			dstNodeName := c.destinationID()
+			_, sourceNamespaced := namespaced[sourceNodeName]
+			_, sourceReferenced := referenced[sourceNodeName]
+			_, dstNamespaced := namespaced[dstNodeName]
+			if sourceNamespaced || sourceReferenced {
+				reference(dstNodeName)
+			}
+			if dstNamespaced {
+				reference(sourceNodeName)
+			}
+		}
+	}
+
+	var nodes []node
+	for _, n := range g.nodes {
+		id := n.id()
+		if _, ok := clusterScoped[id]; ok {
+			if _, ok := referenced[id]; !ok {
+				continue
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	g.nodes = nodes
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// selection is a link from a Kubernetes object to the Pods matched by its label selector.
+// Unlike a connection, the Pods are not known until every object has been populated.
+// Only Pods in the namespace of the source are selected.
+type selection struct {
+	label           string
+	sourceNamespace string
+	sourceName      string
+	sourceKind      string
+	selector        labels.Selector
+}
+
+// resolveSelections replaces each tracked selection with a connection to every Pod matched by its selector.
+// A Pod already reached from the source of the selection through a single intermediate object e.g. a Service through
+// its Endpoints, is not connected again. This way, selections only add the connections missing from stale or absent
+// intermediate objects.
+func (g *Grapher) resolveSelections() {
+	existing := make(map[string]struct{})
+	for _, n := range g.nodes {
+		existing[n.id()] = struct{}{}
+	}
+	successors := make(map[string]map[string]struct{})
+	for _, c := range g.connections {
+		sourceNodeName := c.sourceID()
+		dstNodeName := c.destinationID()
+		if _, ok := existing[sourceNodeName]; !ok {
+			continue
+		}
+		if _, ok := existing[dstNodeName]; !ok {
+			continue
+		}
+		if successors[sourceNodeName] == nil {
+			successors[sourceNodeName] = make(map[string]struct{})
+		}
+		successors[sourceNodeName][dstNodeName] = struct{}{}
+	}
+	// reachable returns whether the destination is reached from the source directly or through a single
+	// intermediate node.
+	reachable := func(sourceNodeName, dstNodeName string) bool {
+		if _, ok := successors[sourceNodeName][dstNodeName]; ok {
+			return true
+		}
+		for intermediate := range successors[sourceNodeName] {
+			if _, ok := successors[intermediate][dstNodeName]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, s := range g.selections {
+		for _, n := range g.nodes {
+			if n.kind != Pod || n.namespace != s.sourceNamespace || !s.selector.Matches(labels.Set(n.object.GetLabels())) {
+				continue
+			}
+			source := connection{sourceNamespace: s.sourceNamespace, sourceName: s.sourceName, sourceKind: s.sourceKind}
+			if reachable(source.sourceID(), n.id()) {
+				continue
+			}
+			g.connections = append(g.connections, connection{
+				label:                s.label,
+				sourceNamespace:      s.sourceNamespace,
+				sourceName:           s.sourceName,
+				sourceKind:           s.sourceKind,
+				destinationNamespace: n.namespace,
+				destinationName:      n.name,
+				destinationKind:      n.kind,
+				selected:             true,
+			})
+		}
+	}
+	g.selections = nil
+}
+
+// isWorkload returns whether the object is a workload selecting the Pods it manages.
+func isWorkload(object *unstructured.Unstructured) bool {
+	if object.GroupVersionKind().Group != "apps" {
+		return false
+	}
+	switch object.GetKind() {
+	case DaemonSet, Deployment, ReplicaSet, StatefulSet:
+		return true
+	}
+	return false
+}
+
+// getWorkloadSelector returns the selector of a workload for the Pods it manages, and whether the object is a workload
+// with a valid selector.
+func getWorkloadSelector(object *unstructured.Unstructured) (labels.Selector, bool) {
+	if !isWorkload(object) {
+		return nil, false
+	}
+	workloadSelector, ok, _ := unstructured.NestedMap(object.Object, "spec", "selector")
+	if !ok {
+		return nil, false
+	}
+	labelSelector := &metav1.LabelSelector{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(workloadSelector, labelSelector)
+	if err != nil {
+		return nil, false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, false
+	}
+	return selector, true
+}
@@ -0,0 +1,194 @@
+package graph
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+)
+
+// newPopulatedGrapher returns a Grapher scaffolded with the namespaces and populated with the objects of the YAML
+// manifests, each ranked by its position in the manifests.
+func newPopulatedGrapher(t *testing.T, namespaces []string, manifests string) *Grapher {
+	t.Helper()
+	g := NewGraph(nil, "")
+	g.Scaffold("test", namespaces, nil)
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for rank := 0; ; rank++ {
+		object := unstructured.Unstructured{}
+		err := decoder.Decode(&object.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to decode manifest: %v", err)
+		}
+		if object.Object == nil {
+			continue
+		}
+		gvk := object.GroupVersionKind()
+		gvr := schema.GroupVersionResource{Group: gvk.Group, Version: gvk.Version, Resource: strings.ToLower(gvk.Kind) + "s"}
+		g.Populate(&unstructured.UnstructuredList{Items: []unstructured.Unstructured{object}}, config.Resource{GroupVersionResource: gvr, Rank: rank})
+	}
+	return g
+}
+
+// getConnections returns each connection of the Grapher matching the filter as "source -> destination", in order,
+// where each is the unquoted ID of its node.
+func getConnections(g *Grapher, filter func(c connection) bool) []string {
+	var connections []string
+	for _, c := range g.connections {
+		if filter(c) {
+			connections = append(connections, strings.Trim(c.sourceID(), `"`)+" -> "+strings.Trim(c.destinationID(), `"`))
+		}
+	}
+	return connections
+}
+
+func TestResolveSelections(t *testing.T) {
+	pods := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: shop
+  labels:
+    app: db
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: other
+  labels:
+    app: web
+---
+`
+	tests := []struct {
+		name      string
+		manifests string
+		want      []string
+	}{
+		{
+			name: "service selects pods in its namespace",
+			manifests: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app: web
+`,
+			want: []string{"Service_shop/web -> Pod_shop/web"},
+		},
+		{
+			name: "service without a selector selects nothing",
+			manifests: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+`,
+		},
+		{
+			name: "pod reached through endpoints is not selected again",
+			manifests: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web
+  namespace: shop
+subsets:
+- addresses:
+  - ip: 10.0.0.1
+    targetRef:
+      kind: Pod
+      name: web
+      namespace: shop
+`,
+		},
+		{
+			name: "empty pod disruption budget selector selects every pod in its namespace",
+			manifests: `
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: all
+  namespace: shop
+spec:
+  selector: {}
+`,
+			want: []string{"PodDisruptionBudget_shop/all -> Pod_shop/web", "PodDisruptionBudget_shop/all -> Pod_shop/db"},
+		},
+		{
+			name: "workload selects pods without an owner",
+			manifests: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: shop
+spec:
+  selector:
+    matchExpressions:
+    - key: app
+      operator: In
+      values: [db]
+`,
+			want: []string{"StatefulSet_shop/db -> Pod_shop/db"},
+		},
+		{
+			name: "workload outside the apps group selects nothing",
+			manifests: `
+apiVersion: example.com/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: web
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPopulatedGrapher(t, []string{"shop", "other"}, pods+tt.manifests)
+			g.resolve()
+			got := getConnections(g, func(c connection) bool { return c.selected })
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("resolveSelections() = %v, want %v", got, tt.want)
+			}
+			if len(g.selections) != 0 {
+				t.Errorf("resolveSelections() left %d selections unresolved", len(g.selections))
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// accessModes are the abbreviations of each access mode, as displayed by kubectl.
+var accessModes = map[corev1.PersistentVolumeAccessMode]string{
+	corev1.ReadWriteOnce:    "RWO",
+	corev1.ReadOnlyMany:     "ROX",
+	corev1.ReadWriteMany:    "RWX",
+	corev1.ReadWriteOncePod: "RWOP",
+}
+
+// getStorageLabel returns the label of a connection representing storage, in the form capacity/access modes/phase
+// e.g. "10Gi/RWO,RWX/Bound". Fields which are not known are omitted.
+func getStorageLabel(capacity corev1.ResourceList, modes []corev1.PersistentVolumeAccessMode, phase string) string {
+	var parts []string
+	if storage, ok := capacity[corev1.ResourceStorage]; ok {
+		parts = append(parts, storage.String())
+	}
+	var abbreviations []string
+	for _, mode := range modes {
+		abbreviation, ok := accessModes[mode]
+		if !ok {
+			abbreviation = string(mode)
+		}
+		abbreviations = append(abbreviations, abbreviation)
+	}
+	if len(abbreviations) > 0 {
+		parts = append(parts, strings.Join(abbreviations, ","))
+	}
+	if phase != "" {
+		parts = append(parts, phase)
+	}
+	return strings.Join(parts, "/")
+}
+
+// getClaimCapacity returns the capacity of a PersistentVolumeClaim, or the capacity it requests until it is bound.
+func getClaimCapacity(claim *corev1.PersistentVolumeClaim) corev1.ResourceList {
+	if _, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+		return claim.Status.Capacity
+	}
+	return claim.Spec.Resources.Requests
+}
+
+// getClaimNames returns the names of the PersistentVolumeClaims created from a volume claim template of a
+// StatefulSet, one for each of its ordinals e.g. "data-web-0".
+func getClaimNames(template, statefulSet string, start, replicas int32) []string {
+	var names []string
+	for ordinal := start; ordinal < start+replicas; ordinal++ {
+		names = append(names, fmt.Sprintf("%s-%s-%d", template, statefulSet, ordinal))
+	}
+	return names
+}
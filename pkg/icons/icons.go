@@ -0,0 +1,254 @@
+package icons
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/AyCarlito/kube-visualization/assets"
+)
+
+const (
+	// iconsDirectory is the directory of the embedded icons.
+	iconsDirectory = "icons"
+	// genericIcon is the name of the icon used for resources without an icon of their own, which are most likely to
+	// be custom resources.
+	genericIcon = "crd"
+)
+
+// Source describes where the icon for a resource was resolved from.
+type Source int
+
+const (
+	// Configured icons are those configured for the resource.
+	Configured Source = iota
+	// Exact icons are named after the resource.
+	Exact
+	// Group icons are named after the API group of the resource.
+	Group
+	// Generic icons are used when no other icon could be found.
+	Generic
+)
+
+// String returns the string representation of the Source.
+func (s Source) String() string {
+	return [...]string{"configured", "exact", "group", "generic"}[s]
+}
+
+// OptFunc is a function that mutates a resolverOpts.
+type OptFunc func(*resolverOpts)
+
+// resolverOpts are the configuration options for the Resolver.
+type resolverOpts struct {
+	cacheDir string
+}
+
+// defaultOpts return the default configuration options for a Resolver.
+func defaultOpts() resolverOpts {
+	return resolverOpts{}
+}
+
+// WithCacheDir sets the directory the embedded icons are written to, which defaults to a directory in the user cache
+// directory. Paths to the embedded icons are relative if it is, so output referencing them renders wherever the
+// directory exists relative to the working directory e.g. on another machine with a checkout of the same repository.
+func WithCacheDir(dir string) OptFunc {
+	return func(o *resolverOpts) {
+		o.cacheDir = dir
+	}
+}
+
+// Resolver resolves the icon representing a resource to a path on the filesystem, as required by Graphviz.
+// Icons in the override directory take precedence over those embedded in the binary.
+type Resolver struct {
+	overrideDir string
+	cacheDir    string
+	configured  map[schema.GroupResource]string
+	mu          sync.Mutex
+	// resolved are the icons already resolved, keyed by resource, sparing the filesystem lookups for each object.
+	resolved map[schema.GroupResource]resolution
+}
+
+// resolution is the icon resolved for a resource.
+type resolution struct {
+	path   string
+	source Source
+}
+
+// NewResolver returns a new *Resolver, preferring icons in the override directory, if not empty.
+// Configured icons are paths to the icon for specific resources, or the names of other icons, and take precedence
+// over all others.
+// The embedded icons are written to a cache directory on the filesystem, so that they may be referenced by path. By
+// default, this is in the user cache directory, or a temporary directory if it cannot be written to.
+func NewResolver(overrideDir string, configured map[schema.GroupResource]string, opts ...OptFunc) (*Resolver, error) {
+	o := defaultOpts()
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	// The user cache directory may be unwritable e.g. in a container run as an arbitrary user without a home
+	// directory, in which case the icons are cached in a temporary directory of the user instead.
+	cacheDirs := []string{o.cacheDir}
+	if o.cacheDir == "" {
+		cacheDirs = nil
+		userCacheDir, err := os.UserCacheDir()
+		if err == nil {
+			cacheDirs = append(cacheDirs, filepath.Join(userCacheDir, "kube-visualization", iconsDirectory))
+		}
+		cacheDirs = append(cacheDirs, filepath.Join(os.TempDir(), fmt.Sprintf("kube-visualization-%d", os.Getuid()), iconsDirectory))
+	}
+	var cacheDir string
+	var err error
+	for _, cacheDir = range cacheDirs {
+		err = cacheIcons(cacheDir)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{
+		overrideDir: overrideDir,
+		cacheDir:    cacheDir,
+		configured:  configured,
+		resolved:    make(map[schema.GroupResource]resolution),
+	}, nil
+}
+
+// cacheIcons writes the embedded icons to the cache directory, creating it if necessary.
+func cacheIcons(cacheDir string) error {
+	err := os.MkdirAll(cacheDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create icon cache directory: %v", err)
+	}
+
+	entries, err := fs.ReadDir(assets.Icons, iconsDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to read embedded icons: %v", err)
+	}
+	for _, entry := range entries {
+		content, err := fs.ReadFile(assets.Icons, iconsDirectory+"/"+entry.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read embedded icon: %v", err)
+		}
+		// Skip icons already cached by a previous run.
+		path := filepath.Join(cacheDir, entry.Name())
+		if cached, err := os.ReadFile(path); err == nil && bytes.Equal(cached, content) {
+			continue
+		}
+		err = writeAtomically(path, content)
+		if err != nil {
+			return fmt.Errorf("failed to cache embedded icon: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeAtomically writes content to a file by renaming a temporary file into its place, so that concurrent runs never
+// read a partially written icon.
+func writeAtomically(path string, content []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(f.Name(), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// isBuiltIn returns whether the API group is built into Kubernetes.
+// Custom resources must be in a group containing a dot, and the "k8s.io" domain is reserved for Kubernetes itself.
+func isBuiltIn(group string) bool {
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+// find returns the path to the icon with the given name, and whether it exists.
+func (r *Resolver) find(name string) (string, bool) {
+	for _, dir := range []string{r.overrideDir, r.cacheDir} {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name+".png")
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Resolve returns the path to the icon for a resource, and where it was resolved from.
+// In order of precedence, the icon is:
+//   - The icon configured for the resource, either a path or the name of another icon e.g. "ingresses.png".
+//   - The icon named after the resource and its group e.g. "certificates.cert-manager.io.png".
+//   - The icon named after the resource e.g. "pods.png", for resources built into Kubernetes only. This prevents a
+//     custom resource from taking the icon of a built-in resource that happens to share its name.
+//   - The icon named after the group of the resource e.g. "cert-manager.io.png".
+//   - The generic icon.
+//
+// Icons are resolved once per resource, so icons added to the filesystem afterwards are not found.
+func (r *Resolver) Resolve(gr schema.GroupResource) (string, Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res, ok := r.resolved[gr]; ok {
+		return res.path, res.source
+	}
+	path, source := r.resolve(gr)
+	r.resolved[gr] = resolution{path: path, source: source}
+	return path, source
+}
+
+// resolve resolves the icon for a resource from the filesystem, as described by Resolve.
+func (r *Resolver) resolve(gr schema.GroupResource) (string, Source) {
+	if icon, ok := r.configured[gr]; ok {
+		if _, err := os.Stat(icon); err == nil {
+			return icon, Configured
+		}
+		// Otherwise, the configured icon may name another icon, sharing it between resources.
+		if name, ok := strings.CutSuffix(icon, ".png"); ok && filepath.Base(icon) == icon {
+			if path, ok := r.find(name); ok {
+				return path, Configured
+			}
+		}
+	}
+	if gr.Group != "" {
+		if path, ok := r.find(gr.String()); ok {
+			return path, Exact
+		}
+	}
+	if isBuiltIn(gr.Group) {
+		if path, ok := r.find(gr.Resource); ok {
+			return path, Exact
+		}
+	}
+	if gr.Group != "" {
+		if path, ok := r.find(gr.Group); ok {
+			return path, Group
+		}
+	}
+	path, _ := r.find(genericIcon)
+	return path, Generic
+}
+
+// Icon returns the path to the icon for a resource.
+func (r *Resolver) Icon(gr schema.GroupResource) string {
+	path, _ := r.Resolve(gr)
+	return path
+}
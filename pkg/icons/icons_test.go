@@ -0,0 +1,149 @@
+package icons
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNewResolverCacheDir(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	// A file in place of a directory cannot be written to, regardless of the permissions of the user.
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, nil, 0o644)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	userCacheDir := t.TempDir()
+	configuredDir := filepath.Join(t.TempDir(), "icons")
+
+	tests := []struct {
+		name          string
+		userCacheHome string
+		opts          []OptFunc
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "user cache directory",
+			userCacheHome: userCacheDir,
+			want:          filepath.Join(userCacheDir, "kube-visualization", iconsDirectory),
+		},
+		{
+			name:          "unwritable user cache directory falls back to a temporary directory",
+			userCacheHome: filepath.Join(file, "cache"),
+			want:          filepath.Join(tempDir, fmt.Sprintf("kube-visualization-%d", os.Getuid()), iconsDirectory),
+		},
+		{
+			name:          "configured directory",
+			userCacheHome: userCacheDir,
+			opts:          []OptFunc{WithCacheDir(configuredDir)},
+			want:          configuredDir,
+		},
+		{
+			name:          "unwritable configured directory",
+			userCacheHome: userCacheDir,
+			opts:          []OptFunc{WithCacheDir(filepath.Join(file, "icons"))},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", tt.userCacheHome)
+			resolver, err := NewResolver("", nil, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewResolver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resolver.cacheDir != tt.want {
+				t.Errorf("NewResolver() cache directory = %q, want %q", resolver.cacheDir, tt.want)
+			}
+			entries, err := os.ReadDir(resolver.cacheDir)
+			if err != nil || len(entries) == 0 {
+				t.Errorf("NewResolver() cached no icons: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	overrideDir := t.TempDir()
+	for _, name := range []string{"pods.png", "example.com.png"} {
+		err := os.WriteFile(filepath.Join(overrideDir, name), nil, 0o644)
+		if err != nil {
+			t.Fatalf("failed to write icon: %v", err)
+		}
+	}
+	configuredIcon := filepath.Join(t.TempDir(), "deployment.png")
+	err := os.WriteFile(configuredIcon, nil, 0o644)
+	if err != nil {
+		t.Fatalf("failed to write icon: %v", err)
+	}
+	cacheDir := t.TempDir()
+	resolver, err := NewResolver(overrideDir, map[schema.GroupResource]string{
+		{Group: "apps", Resource: "deployments"}:                     configuredIcon,
+		{Group: "discovery.k8s.io", Resource: "endpointslices"}:      "endpoints.png",
+		{Group: "gateway.networking.k8s.io", Resource: "grpcroutes"}: "missing.png",
+	}, WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		gr         schema.GroupResource
+		wantPath   string
+		wantSource Source
+	}{
+		{
+			name:       "configured path",
+			gr:         schema.GroupResource{Group: "apps", Resource: "deployments"},
+			wantPath:   configuredIcon,
+			wantSource: Configured,
+		},
+		{
+			name:       "configured name of another icon",
+			gr:         schema.GroupResource{Group: "discovery.k8s.io", Resource: "endpointslices"},
+			wantPath:   filepath.Join(cacheDir, "endpoints.png"),
+			wantSource: Configured,
+		},
+		{
+			name:       "overridden icon named after the resource",
+			gr:         schema.GroupResource{Resource: "pods"},
+			wantPath:   filepath.Join(overrideDir, "pods.png"),
+			wantSource: Exact,
+		},
+		{
+			name:       "embedded icon named after the resource",
+			gr:         schema.GroupResource{Group: "apps", Resource: "statefulsets"},
+			wantPath:   filepath.Join(cacheDir, "statefulsets.png"),
+			wantSource: Exact,
+		},
+		{
+			name:       "custom resource sharing the name of a built-in resource",
+			gr:         schema.GroupResource{Group: "example.com", Resource: "services"},
+			wantPath:   filepath.Join(overrideDir, "example.com.png"),
+			wantSource: Group,
+		},
+		{
+			name:       "missing configured icon",
+			gr:         schema.GroupResource{Group: "gateway.networking.k8s.io", Resource: "grpcroutes"},
+			wantPath:   filepath.Join(cacheDir, genericIcon+".png"),
+			wantSource: Generic,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, source := resolver.Resolve(tt.gr)
+			if path != tt.wantPath || source != tt.wantSource {
+				t.Errorf("Resolve() = %q, %v, want %q, %v", path, source, tt.wantPath, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+)
+
+// Stdin is the path which, when provided to NewReader, causes manifests to be read from standard input.
+const Stdin = "-"
+
+// manifestExtensions are the file extensions considered to be manifests when walking a directory.
+var manifestExtensions = map[string]struct{}{
+	".yaml": {},
+	".yml":  {},
+	".json": {},
+}
+
+// OptFunc is a function that mutates a readerOpts.
+type OptFunc func(*readerOpts)
+
+// readerOpts are the configuration options for the Reader.
+type readerOpts struct {
+	labelSelector    string
+	defaultNamespace string
+}
+
+// defaultOpts return the default configuration options for a Reader.
+func defaultOpts() readerOpts {
+	return readerOpts{
+		labelSelector:    "",
+		defaultNamespace: "",
+	}
+}
+
+// WithLabelSelector returns an optFunc to mutate the labelSelector configuration option of the Reader.
+func WithLabelSelector(ls string) OptFunc {
+	return func(o *readerOpts) {
+		o.labelSelector = ls
+	}
+}
+
+// WithDefaultNamespace returns an optFunc to mutate the defaultNamespace configuration option of the Reader.
+// Objects without a namespace belong to the default namespace. By default, they belong to whichever namespace is
+// listed.
+func WithDefaultNamespace(ns string) OptFunc {
+	return func(o *readerOpts) {
+		o.defaultNamespace = ns
+	}
+}
+
+// Reader is a client.Lister.
+var _ client.Lister = &Reader{}
+
+// Reader serves objects decoded from YAML or JSON manifests, in place of a Kubernetes cluster.
+type Reader struct {
+	objects  []unstructured.Unstructured
+	selector labels.Selector
+	opts     readerOpts
+}
+
+// NewReader returns a new *Reader.
+// The path may be a single manifest, a directory which is walked recursively for manifests, or Stdin.
+// A manifest may contain several documents, each of which may be a single object or a List of objects as produced
+// by "kubectl get -o yaml".
+func NewReader(path string, opts ...OptFunc) (*Reader, error) {
+	o := defaultOpts()
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	selector, err := labels.Parse(o.labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse label selector: %v", err)
+	}
+
+	r := &Reader{selector: selector, opts: o}
+	if path == Stdin {
+		err = r.decode(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode standard input: %v", err)
+		}
+		return r, nil
+	}
+
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// Files explicitly provided are always read, files found while walking a directory must look like manifests.
+		if p != path {
+			if _, ok := manifestExtensions[strings.ToLower(filepath.Ext(p))]; !ok {
+				return nil
+			}
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return fmt.Errorf("failed to open manifest: %v", err)
+		}
+		defer f.Close()
+		err = r.decode(f)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %v", p, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests: %v", err)
+	}
+
+	return r, nil
+}
+
+// decode decodes every document in the stream, flattening any Lists into their items.
+func (r *Reader) decode(stream io.Reader) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(stream, 4096)
+	for {
+		content := map[string]interface{}{}
+		err := decoder.Decode(&content)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Empty documents e.g. a trailing "---" are skipped.
+		if len(content) == 0 {
+			continue
+		}
+
+		object := unstructured.Unstructured{Object: content}
+		if !object.IsList() {
+			r.objects = append(r.objects, object)
+			continue
+		}
+		err = object.EachListItem(func(item runtime.Object) error {
+			u, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				return fmt.Errorf("unexpected list item type %T", item)
+			}
+			r.objects = append(r.objects, *u)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to flatten list: %v", err)
+		}
+	}
+}
+
+// guessResource returns the resource of a kind, guessed from its name.
+// Unlike other kinds ending in "y" e.g. "NetworkPolicy", kinds ending in a vowel followed by "y" e.g. "Gateway" are
+// pluralised by appending "s".
+func guessResource(gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	kind := strings.ToLower(gvk.Kind)
+	if len(kind) > 1 && strings.HasSuffix(kind, "y") && strings.ContainsAny(kind[len(kind)-2:len(kind)-1], "aeiou") {
+		return gvk.GroupVersion().WithResource(kind + "s")
+	}
+	resource, _ := meta.UnsafeGuessKindToResource(gvk)
+	return resource
+}
+
+// List returns a list of objects in a namespace for a given GVR.
+// Objects are matched on group and resource, the resource being derived from the kind of the object. The version is
+// not considered, as rendered manifests frequently use a different version to the one configured.
+// Objects without a namespace are assumed to belong to the default namespace, as they would if applied with
+// "kubectl apply --namespace", or to the requested namespace if there is no default.
+// An empty namespace lists the objects of a cluster-scoped GVR, which have no namespace, and to which the label
+// selector does not apply.
+func (r *Reader) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{}
+	for _, object := range r.objects {
+		objectResource := guessResource(object.GroupVersionKind())
+		if objectResource.Group != gvr.Group || objectResource.Resource != gvr.Resource {
+			continue
+		}
+		if namespace == "" {
+			if object.GetNamespace() == "" {
+				list.Items = append(list.Items, *object.DeepCopy())
+			}
+			continue
+		}
+		objectNamespace := object.GetNamespace()
+		if objectNamespace == "" {
+			objectNamespace = r.opts.defaultNamespace
+		}
+		if objectNamespace != "" && objectNamespace != namespace {
+			continue
+		}
+		if !r.selector.Matches(labels.Set(object.GetLabels())) {
+			continue
+		}
+		item := object.DeepCopy()
+		item.SetNamespace(namespace)
+		list.Items = append(list.Items, *item)
+	}
+	return list, nil
+}
+
+// Namespaces returns the namespaces of the objects in the manifests, in alphabetical order, including the default
+// namespace if any object is without a namespace.
+func (r *Reader) Namespaces() []string {
+	unique := make(map[string]struct{})
+	for _, object := range r.objects {
+		namespace := object.GetNamespace()
+		if namespace == "" {
+			namespace = r.opts.defaultNamespace
+		}
+		if namespace != "" {
+			unique[namespace] = struct{}{}
+		}
+	}
+	namespaces := []string{}
+	for namespace := range unique {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
@@ -0,0 +1,271 @@
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// writeManifests writes each of the manifests, keyed by name, to a temporary directory, returning its path.
+func writeManifests(t *testing.T, manifests map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range manifests {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestGuessResource(t *testing.T) {
+	tests := []struct {
+		name string
+		gvk  schema.GroupVersionKind
+		want schema.GroupVersionResource
+	}{
+		{
+			name: "core kind",
+			gvk:  schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			want: schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+		},
+		{
+			name: "kind ending in a consonant followed by y",
+			gvk:  schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+			want: schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+		},
+		{
+			name: "kind ending in a vowel followed by y",
+			gvk:  schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"},
+			want: schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"},
+		},
+		{
+			name: "kind ending in s",
+			gvk:  schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+			want: schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+		},
+		{
+			name: "plural kind",
+			gvk:  schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"},
+			want: schema.GroupVersionResource{Version: "v1", Resource: "endpoints"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := guessResource(tt.gvk)
+			if got != tt.want {
+				t.Errorf("guessResource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderList(t *testing.T) {
+	manifests := map[string]string{
+		"app.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+---
+`,
+		"list.yaml": `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+    labels:
+      app: web
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+`,
+		"node.json": `{"apiVersion": "v1", "kind": "Node", "metadata": {"name": "node-1"}}`,
+		"README.md": `not a manifest`,
+	}
+
+	tests := []struct {
+		name          string
+		opts          []OptFunc
+		gvr           schema.GroupVersionResource
+		namespace     string
+		wantNames     []string
+		wantNamespace string
+	}{
+		{
+			name:          "object without a namespace belongs to the default namespace",
+			opts:          []OptFunc{WithDefaultNamespace("shop")},
+			gvr:           schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			namespace:     "shop",
+			wantNames:     []string{"web"},
+			wantNamespace: "shop",
+		},
+		{
+			name:      "object without a namespace is not in another namespace",
+			opts:      []OptFunc{WithDefaultNamespace("shop")},
+			gvr:       schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			namespace: "other",
+		},
+		{
+			name:          "object without a namespace belongs to any namespace without a default",
+			gvr:           schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			namespace:     "other",
+			wantNames:     []string{"web"},
+			wantNamespace: "other",
+		},
+		{
+			name:      "object in another namespace",
+			gvr:       schema.GroupVersionResource{Version: "v1", Resource: "services"},
+			namespace: "other",
+		},
+		{
+			name:          "version is not considered",
+			gvr:           schema.GroupVersionResource{Version: "v2", Resource: "services"},
+			namespace:     "shop",
+			wantNames:     []string{"web"},
+			wantNamespace: "shop",
+		},
+		{
+			name:          "list is flattened",
+			gvr:           schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			namespace:     "shop",
+			wantNames:     []string{"a", "b"},
+			wantNamespace: "shop",
+		},
+		{
+			name:          "label selector",
+			opts:          []OptFunc{WithLabelSelector("app=web")},
+			gvr:           schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			namespace:     "shop",
+			wantNames:     []string{"a"},
+			wantNamespace: "shop",
+		},
+		{
+			name:      "cluster-scoped JSON, to which the label selector does not apply",
+			opts:      []OptFunc{WithLabelSelector("app=web")},
+			gvr:       schema.GroupVersionResource{Version: "v1", Resource: "nodes"},
+			wantNames: []string{"node-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(writeManifests(t, manifests), tt.opts...)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			list, err := reader.List(context.Background(), tt.gvr, tt.namespace)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var names []string
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
+				if item.GetNamespace() != tt.wantNamespace {
+					t.Errorf("List() namespace of %s = %q, want %q", item.GetName(), item.GetNamespace(), tt.wantNamespace)
+				}
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("List() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestReaderNamespaces(t *testing.T) {
+	manifests := map[string]string{
+		"app.yaml": `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-1
+`,
+		"data.yaml": `
+apiVersion: v1
+kind: Service
+metadata:
+  name: db
+  namespace: data
+`,
+	}
+	tests := []struct {
+		name string
+		opts []OptFunc
+		want []string
+	}{
+		{
+			name: "objects without a namespace belong to the default namespace",
+			opts: []OptFunc{WithDefaultNamespace("default")},
+			want: []string{"data", "default", "shop"},
+		},
+		{
+			name: "objects without a namespace are ignored without a default namespace",
+			want: []string{"data", "shop"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(writeManifests(t, manifests), tt.opts...)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			if got := reader.Namespaces(); !slices.Equal(got, tt.want) {
+				t.Errorf("Namespaces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests map[string]string
+		opts      []OptFunc
+	}{
+		{
+			name:      "invalid manifest",
+			manifests: map[string]string{"invalid.yaml": "kind: [Pod"},
+		},
+		{
+			name:      "invalid label selector",
+			manifests: map[string]string{},
+			opts:      []OptFunc{WithLabelSelector("app in web")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(writeManifests(t, tt.manifests), tt.opts...)
+			if err == nil {
+				t.Errorf("NewReader() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+	"github.com/AyCarlito/kube-visualization/pkg/graph"
+)
+
+// shutdownTimeout defines the timeout for in-flight requests to complete when the server is shut down.
+const shutdownTimeout = 5 * time.Second
+
+// index is the interactive viewer, showing the graph rendered by the server.
+//
+//go:embed index.html
+var index []byte
+
+// Server serves the most recently published graph over HTTP, alongside an interactive viewer for it.
+// The graph is rendered to SVG by Graphviz on the server, so that the viewer needs no third-party scripts.
+// Browsers viewing the graph are notified through server-sent events whenever a new graph is published.
+type Server struct {
+	address string
+	icons   graph.IconResolver
+	mu      sync.RWMutex
+	// iconURLs are the URLs relative to the viewer of the icons resolved for published graphs, keyed by their path.
+	iconURLs    map[string]string
+	dot         string
+	svg         []byte
+	objects     map[string]*unstructured.Unstructured
+	subscribers map[chan struct{}]struct{}
+}
+
+// NewServer returns a new *Server, listening on address and serving the icons resolved by i.
+func NewServer(address string, i graph.IconResolver) *Server {
+	return &Server{
+		address:     address,
+		icons:       i,
+		iconURLs:    make(map[string]string),
+		objects:     make(map[string]*unstructured.Unstructured),
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Server is a graph.IconResolver.
+var _ graph.IconResolver = &Server{}
+
+// Icon returns the path to the icon for a given resource, as resolved by the IconResolver of the Server.
+// Graphs published to the Server must use it to resolve their icons, so that the paths read by Graphviz can be
+// replaced by URLs the browser can fetch.
+func (s *Server) Icon(gr schema.GroupResource) string {
+	path := s.icons.Icon(gr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.iconURLs[path] = "assets/" + url.PathEscape(gr.String()) + ".png"
+	return path
+}
+
+// Publish renders g to SVG and replaces the graph being served with it, notifying any browsers viewing it.
+// It satisfies visualizer.Publisher.
+func (s *Server) Publish(g *graph.Grapher) error {
+	dot := g.String()
+	svg, err := graph.Render(dot, graph.SVG)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replacements []string
+	for path, iconURL := range s.iconURLs {
+		replacements = append(replacements, `"`+html.EscapeString(path)+`"`, `"`+iconURL+`"`)
+	}
+	s.dot = dot
+	s.svg = []byte(strings.NewReplacer(replacements...).Replace(string(svg)))
+	s.objects = g.Objects()
+	for subscriber := range s.subscribers {
+		// Subscribers only need to know that the graph has changed, not how many times.
+		select {
+		case subscriber <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Handler returns the http.Handler serving the viewer and the graph.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /graph.dot", s.handleGraph)
+	mux.HandleFunc("GET /graph.svg", s.handleSVG)
+	mux.HandleFunc("GET /objects/{id}", s.handleObject)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /assets/{icon}", s.handleIcon)
+	return mux
+}
+
+// ListenAndServe serves HTTP requests until the context is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	server := &http.Server{Addr: s.address, Handler: s.Handler()}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to serve: %v", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cxl := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cxl()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down server: %v", err)
+	}
+	return nil
+}
+
+// handleIndex serves the viewer.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(index)
+}
+
+// handleIcon serves the icon for a resource.
+func (s *Server) handleIcon(w http.ResponseWriter, r *http.Request) {
+	resource, ok := strings.CutSuffix(r.PathValue("icon"), ".png")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, s.icons.Icon(schema.ParseGroupResource(resource)))
+}
+
+// handleSVG serves the graph rendered to SVG.
+func (s *Server) handleSVG(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(s.svg)
+}
+
+// handleGraph serves the string representation of the graph.
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(s.dot))
+}
+
+// handleObject serves the object represented by a node in the graph.
+// Managed fields are omitted, as they are rarely of interest and dwarf the rest of the object. Sensitive fields are
+// redacted.
+func (s *Server) handleObject(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	object, ok := s.objects[r.PathValue("id")]
+	s.mu.RUnlock()
+	if !ok {
+		http.Error(w, "object not found", http.StatusNotFound)
+		return
+	}
+
+	object = object.DeepCopy()
+	object.SetManagedFields(nil)
+	client.Redact(object)
+	content, err := json.MarshalIndent(object.Object, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to marshal object: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(content)
+}
+
+// handleEvents streams a server-sent event to the browser whenever a new graph is published.
+// An event is sent upon connection, so that a reconnecting browser catches up on anything it missed.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	subscriber := make(chan struct{}, 1)
+	subscriber <- struct{}{}
+	s.mu.Lock()
+	s.subscribers[subscriber] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, subscriber)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-subscriber:
+			_, err := fmt.Fprint(w, "event: update\ndata: {}\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestHandleObject(t *testing.T) {
+	tests := []struct {
+		name       string
+		object     map[string]interface{}
+		wantStatus int
+		want       map[string]interface{}
+	}{
+		{
+			name: "secret data is redacted",
+			object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   map[string]interface{}{"name": "db", "namespace": "shop"},
+				"data":       map[string]interface{}{"password": "aHVudGVyMg=="},
+			},
+			wantStatus: http.StatusOK,
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   map[string]interface{}{"name": "db", "namespace": "shop"},
+				"data":       map[string]interface{}{"password": "REDACTED"},
+			},
+		},
+		{
+			name: "last applied configuration is redacted and managed fields are omitted",
+			object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "config",
+					"namespace": "shop",
+					"annotations": map[string]interface{}{
+						"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"host":"db"}}`,
+						"team": "a",
+					},
+					"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+				},
+				"data": map[string]interface{}{"host": "db"},
+			},
+			wantStatus: http.StatusOK,
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "config",
+					"namespace": "shop",
+					"annotations": map[string]interface{}{
+						"kubectl.kubernetes.io/last-applied-configuration": "REDACTED",
+						"team": "a",
+					},
+				},
+				"data": map[string]interface{}{"host": "db"},
+			},
+		},
+		{
+			name:       "object not in the graph is not found",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("", nil)
+			var object *unstructured.Unstructured
+			if tt.object != nil {
+				object = &unstructured.Unstructured{Object: tt.object}
+				s.objects["object"] = object
+			}
+			original := object.DeepCopy()
+
+			recorder := httptest.NewRecorder()
+			s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/objects/object", nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("handleObject() status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+			var got map[string]interface{}
+			err := json.Unmarshal(recorder.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("failed to unmarshal object: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleObject() = %v, want %v", got, tt.want)
+			}
+			// The object published in the graph is served, but not modified.
+			if !reflect.DeepEqual(object, original) {
+				t.Errorf("handleObject() modified the object to %v", object.Object)
+			}
+		})
+	}
+}
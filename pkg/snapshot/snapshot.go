@@ -0,0 +1,339 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+)
+
+const (
+	// configFileName is the name of the configuration file within a snapshot archive.
+	configFileName = "config.json"
+	// metadataFileName is the name of the metadata file within a snapshot archive.
+	metadataFileName = "metadata.json"
+	// resourcesDirectory is the directory containing the objects within a snapshot archive.
+	// Objects are stored by namespace, group, version and resource e.g. "resources/default/apps/v1/deployments.json".
+	// Cluster-scoped objects are stored under the cluster scope e.g. "resources/_cluster/core/v1/persistentvolumes.json".
+	resourcesDirectory = "resources"
+	// coreGroup is the directory name used for the core group, whose name is empty.
+	coreGroup = "core"
+	// clusterScope is the directory name used for cluster-scoped objects, whose namespace is empty.
+	// Namespace names cannot contain an underscore, so it cannot clash with a namespace.
+	clusterScope = "_cluster"
+)
+
+// metadata describes the capture of a snapshot.
+type metadata struct {
+	Namespaces    []string  `json:"namespaces"`
+	LabelSelector string    `json:"labelSelector,omitempty"`
+	Created       time.Time `json:"created"`
+	// Namespace is the only namespace of snapshots saved before several namespaces could be visualized.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// key identifies a list of objects within a snapshot.
+type key struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+// path returns the path of the file holding the list of objects within a snapshot archive.
+func (k key) path() string {
+	group := k.gvr.Group
+	if group == "" {
+		group = coreGroup
+	}
+	namespace := k.namespace
+	if namespace == "" {
+		namespace = clusterScope
+	}
+	return path.Join(resourcesDirectory, namespace, group, k.gvr.Version, k.gvr.Resource+".json")
+}
+
+// keyFromPath returns the key of a list of objects from its path within a snapshot archive.
+func keyFromPath(p string) (key, error) {
+	parts := strings.Split(strings.TrimSuffix(p, ".json"), "/")
+	if len(parts) != 5 || parts[0] != resourcesDirectory {
+		return key{}, fmt.Errorf("unexpected file in snapshot: %s", p)
+	}
+	group := parts[2]
+	if group == coreGroup {
+		group = ""
+	}
+	namespace := parts[1]
+	if namespace == clusterScope {
+		namespace = ""
+	}
+	return key{gvr: schema.GroupVersionResource{Group: group, Version: parts[3], Resource: parts[4]}, namespace: namespace}, nil
+}
+
+// Recorder is a client.AllNamespacesLister.
+var _ client.AllNamespacesLister = &Recorder{}
+
+// Recorder records every object returned by another client.Lister, so that it may be saved as a snapshot.
+// Snapshots are meant to be shared, so the sensitive fields of the objects are redacted, as by client.Redact.
+type Recorder struct {
+	lister client.Lister
+	mu     sync.Mutex
+	lists  map[key]*unstructured.UnstructuredList
+}
+
+// NewRecorder returns a new *Recorder, recording the objects returned by l.
+func NewRecorder(l client.Lister) *Recorder {
+	return &Recorder{lister: l, lists: make(map[key]*unstructured.UnstructuredList)}
+}
+
+// List returns a list of objects in a namespace for a given GVR from the underlying client.Lister, recording it.
+func (r *Recorder) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	list, err := r.lister.List(ctx, gvr, namespace)
+	if err != nil {
+		return nil, err
+	}
+	recorded := list.DeepCopy()
+	for i := range recorded.Items {
+		client.Redact(&recorded.Items[i])
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lists[key{gvr: gvr, namespace: namespace}] = recorded
+	return list, nil
+}
+
+// ListAllNamespaces returns a list of the objects of a namespaced GVR in every namespace from the underlying
+// client.Lister, recording the objects of each namespace as if listed from it. The underlying client.Lister must be a
+// client.AllNamespacesLister.
+func (r *Recorder) ListAllNamespaces(ctx context.Context, gvr schema.GroupVersionResource) (*unstructured.UnstructuredList, error) {
+	lister, ok := r.lister.(client.AllNamespacesLister)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot list every namespace at once", r.lister)
+	}
+	list, err := lister.ListAllNamespaces(ctx, gvr)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, object := range list.Items {
+		k := key{gvr: gvr, namespace: object.GetNamespace()}
+		if r.lists[k] == nil {
+			r.lists[k] = &unstructured.UnstructuredList{}
+		}
+		recorded := object.DeepCopy()
+		client.Redact(recorded)
+		r.lists[k].Items = append(r.lists[k].Items, *recorded)
+	}
+	return list, nil
+}
+
+// Save writes the recorded objects, along with the configuration used to gather them, to a gzipped tarball at path.
+func (r *Recorder) Save(path string, cfg *config.Config, namespaces []string, labelSelector string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Objects other than Secrets may still be sensitive, so only the owner may read the snapshot.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	defer f.Close()
+
+	gzipWriter := gzip.NewWriter(f)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	created := time.Now().UTC()
+	files := map[string]interface{}{
+		configFileName:   cfg,
+		metadataFileName: metadata{Namespaces: namespaces, LabelSelector: labelSelector, Created: created},
+	}
+	for k, list := range r.lists {
+		items := []map[string]interface{}{}
+		for _, item := range list.Items {
+			items = append(items, item.Object)
+		}
+		files[k.path()] = items
+	}
+
+	// Write in a stable order, with every file modified when the snapshot was created, so that snapshots of the same
+	// objects differ only in the time of their creation.
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %v", name, err)
+		}
+		err = tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), ModTime: created})
+		if err != nil {
+			return fmt.Errorf("failed to write header for %s: %v", name, err)
+		}
+		_, err = tarWriter.Write(content)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close snapshot archive: %v", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close snapshot compression: %v", err)
+	}
+	return nil
+}
+
+// OptFunc is a function that mutates a snapshotOpts.
+type OptFunc func(*snapshotOpts)
+
+// snapshotOpts are the configuration options for the Snapshot.
+type snapshotOpts struct {
+	labelSelector string
+}
+
+// defaultOpts return the default configuration options for a Snapshot.
+func defaultOpts() snapshotOpts {
+	return snapshotOpts{
+		labelSelector: "",
+	}
+}
+
+// WithLabelSelector returns an optFunc to mutate the labelSelector configuration option of the Snapshot.
+// The label selector further filters the objects recorded in the snapshot.
+func WithLabelSelector(ls string) OptFunc {
+	return func(o *snapshotOpts) {
+		o.labelSelector = ls
+	}
+}
+
+// Snapshot is a client.Lister.
+var _ client.Lister = &Snapshot{}
+
+// Snapshot serves the objects of a previously saved snapshot, in place of a Kubernetes cluster.
+type Snapshot struct {
+	// Config is the configuration used to gather the objects in the snapshot.
+	Config *config.Config
+	// Namespaces are the namespaces the objects in the snapshot were gathered from.
+	Namespaces []string
+	lists      map[key]*unstructured.UnstructuredList
+	selector   labels.Selector
+}
+
+// Load reads a snapshot from the gzipped tarball at path and returns a new *Snapshot.
+func Load(path string, opts ...OptFunc) (*Snapshot, error) {
+	o := defaultOpts()
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	selector, err := labels.Parse(o.labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse label selector: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file: %v", err)
+	}
+	defer f.Close()
+
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %v", err)
+	}
+	defer gzipReader.Close()
+
+	s := &Snapshot{lists: make(map[key]*unstructured.UnstructuredList), selector: selector}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot archive: %v", err)
+		}
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", header.Name, err)
+		}
+
+		switch header.Name {
+		case configFileName:
+			s.Config = &config.Config{}
+			err = json.Unmarshal(content, s.Config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal snapshot configuration: %v", err)
+			}
+		case metadataFileName:
+			m := metadata{}
+			err = json.Unmarshal(content, &m)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal snapshot metadata: %v", err)
+			}
+			s.Namespaces = m.Namespaces
+			if len(s.Namespaces) == 0 && m.Namespace != "" {
+				s.Namespaces = []string{m.Namespace}
+			}
+		default:
+			k, err := keyFromPath(header.Name)
+			if err != nil {
+				return nil, err
+			}
+			items := []map[string]interface{}{}
+			err = json.Unmarshal(content, &items)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s: %v", header.Name, err)
+			}
+			list := &unstructured.UnstructuredList{}
+			for _, item := range items {
+				list.Items = append(list.Items, unstructured.Unstructured{Object: item})
+			}
+			s.lists[k] = list
+		}
+	}
+
+	if s.Config == nil {
+		return nil, fmt.Errorf("snapshot does not contain a configuration")
+	}
+	return s, nil
+}
+
+// List returns a list of objects in a namespace for a given GVR, as recorded in the snapshot.
+// A GVR that was not recorded e.g. one added to the configuration since, yields an empty list rather than an error,
+// so that snapshots remain usable as the configuration evolves.
+func (s *Snapshot) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{}
+	recorded, ok := s.lists[key{gvr: gvr, namespace: namespace}]
+	if !ok {
+		return list, nil
+	}
+	for _, object := range recorded.Items {
+		// As when listing from a cluster, the label selector does not apply to cluster-scoped objects.
+		if namespace != "" && !s.selector.Matches(labels.Set(object.GetLabels())) {
+			continue
+		}
+		list.Items = append(list.Items, *object.DeepCopy())
+	}
+	return list, nil
+}
@@ -0,0 +1,357 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+)
+
+var (
+	pods              = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	deployments       = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	persistentVolumes = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumes"}
+)
+
+// newObject returns an object of a kind with the given namespace, name and labels.
+func newObject(kind, namespace, name string, objectLabels map[string]string) unstructured.Unstructured {
+	object := unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind(kind)
+	object.SetNamespace(namespace)
+	object.SetName(name)
+	object.SetLabels(objectLabels)
+	return object
+}
+
+// newLister returns a client.Lister serving the lists, keyed by GVR and namespace.
+func newLister(lists map[key][]unstructured.Unstructured) client.Lister {
+	return client.ListerFunc(func(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+		return &unstructured.UnstructuredList{Items: lists[key{gvr: gvr, namespace: namespace}]}, nil
+	})
+}
+
+// names returns the names of the objects in a list.
+func names(list *unstructured.UnstructuredList) []string {
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	return names
+}
+
+func TestKeyPath(t *testing.T) {
+	tests := []struct {
+		name string
+		key  key
+		want string
+	}{
+		{
+			name: "core group",
+			key:  key{gvr: pods, namespace: "shop"},
+			want: "resources/shop/core/v1/pods.json",
+		},
+		{
+			name: "named group",
+			key:  key{gvr: deployments, namespace: "shop"},
+			want: "resources/shop/apps/v1/deployments.json",
+		},
+		{
+			name: "cluster-scoped",
+			key:  key{gvr: persistentVolumes},
+			want: "resources/_cluster/core/v1/persistentvolumes.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.key.path()
+			if got != tt.want {
+				t.Errorf("path() = %q, want %q", got, tt.want)
+			}
+			k, err := keyFromPath(got)
+			if err != nil {
+				t.Fatalf("keyFromPath() error = %v", err)
+			}
+			if k != tt.key {
+				t.Errorf("keyFromPath() = %v, want %v", k, tt.key)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	recorder := NewRecorder(newLister(map[key][]unstructured.Unstructured{
+		{gvr: pods, namespace: "shop"}: {
+			newObject("Pod", "shop", "web", map[string]string{"app": "web"}),
+			newObject("Pod", "shop", "db", map[string]string{"app": "db"}),
+		},
+		{gvr: persistentVolumes}: {
+			newObject("PersistentVolume", "", "data", map[string]string{"app": "db"}),
+		},
+	}))
+	for _, k := range []key{{gvr: pods, namespace: "shop"}, {gvr: deployments, namespace: "shop"}, {gvr: persistentVolumes}} {
+		_, err := recorder.List(context.Background(), k.gvr, k.namespace)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	}
+	cfg := &config.Config{Resources: []config.Resource{{GroupVersionResource: pods, Rank: 10}}}
+	path := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	err := recorder.Save(path, cfg, []string{"shop"}, "")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		labelSelector string
+		key           key
+		want          []string
+	}{
+		{
+			name: "recorded objects",
+			key:  key{gvr: pods, namespace: "shop"},
+			want: []string{"web", "db"},
+		},
+		{
+			name:          "label selector",
+			labelSelector: "app=web",
+			key:           key{gvr: pods, namespace: "shop"},
+			want:          []string{"web"},
+		},
+		{
+			name:          "label selector does not apply to cluster-scoped objects",
+			labelSelector: "app=web",
+			key:           key{gvr: persistentVolumes},
+			want:          []string{"data"},
+		},
+		{
+			name: "recorded empty list",
+			key:  key{gvr: deployments, namespace: "shop"},
+		},
+		{
+			name: "unrecorded namespace",
+			key:  key{gvr: pods, namespace: "other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap, err := Load(path, WithLabelSelector(tt.labelSelector))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !slices.Equal(snap.Namespaces, []string{"shop"}) {
+				t.Errorf("Load() namespaces = %v, want [shop]", snap.Namespaces)
+			}
+			if len(snap.Config.Resources) != 1 || snap.Config.Resources[0].GroupVersionResource != pods {
+				t.Errorf("Load() configuration = %v, want %v", snap.Config, cfg)
+			}
+			list, err := snap.List(context.Background(), tt.key.gvr, tt.key.namespace)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if got := names(list); !slices.Equal(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveRedacts(t *testing.T) {
+	secret := newObject("Secret", "shop", "db", nil)
+	secret.SetAnnotations(map[string]string{"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"password":"aHVudGVyMg=="}}`})
+	unstructured.SetNestedStringMap(secret.Object, map[string]string{"password": "aHVudGVyMg=="}, "data")
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	recorder := NewRecorder(newLister(map[key][]unstructured.Unstructured{{gvr: secrets, namespace: "shop"}: {secret}}))
+	listed, err := recorder.List(context.Background(), secrets, "shop")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	err = recorder.Save(path, &config.Config{}, []string{"shop"}, "")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat snapshot: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Save() mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+	snap, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	list, err := snap.List(context.Background(), secrets, "shop")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		object unstructured.Unstructured
+		want   string
+	}{
+		{name: "saved", object: list.Items[0], want: "REDACTED"},
+		{name: "listed", object: listed.Items[0], want: "aHVudGVyMg=="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			password, _, _ := unstructured.NestedString(tt.object.Object, "data", "password")
+			if password != tt.want {
+				t.Errorf("data = %q, want %q", password, tt.want)
+			}
+			annotation := tt.object.GetAnnotations()["kubectl.kubernetes.io/last-applied-configuration"]
+			if (annotation == "REDACTED") != (tt.want == "REDACTED") {
+				t.Errorf("last applied configuration = %q", annotation)
+			}
+		})
+	}
+}
+
+// allNamespacesLister is a client.AllNamespacesLister listing the objects of every namespace from a client.Lister.
+type allNamespacesLister struct {
+	client.Lister
+	namespaces []string
+}
+
+func (l allNamespacesLister) ListAllNamespaces(ctx context.Context, gvr schema.GroupVersionResource) (*unstructured.UnstructuredList, error) {
+	all := &unstructured.UnstructuredList{}
+	for _, namespace := range l.namespaces {
+		list, err := l.List(ctx, gvr, namespace)
+		if err != nil {
+			return nil, err
+		}
+		all.Items = append(all.Items, list.Items...)
+	}
+	return all, nil
+}
+
+func TestRecorderListAllNamespaces(t *testing.T) {
+	lister := newLister(map[key][]unstructured.Unstructured{
+		{gvr: pods, namespace: "shop"}: {newObject("Pod", "shop", "web", nil)},
+		{gvr: pods, namespace: "data"}: {newObject("Pod", "data", "db", nil), newObject("Pod", "data", "cache", nil)},
+	})
+	tests := []struct {
+		name      string
+		lister    client.Lister
+		namespace string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "objects are recorded in their namespace",
+			lister:    allNamespacesLister{Lister: lister, namespaces: []string{"shop", "data"}},
+			namespace: "data",
+			want:      []string{"db", "cache"},
+		},
+		{
+			name:    "lister cannot list every namespace at once",
+			lister:  lister,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := NewRecorder(tt.lister)
+			_, err := recorder.ListAllNamespaces(context.Background(), pods)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListAllNamespaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			path := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+			err = recorder.Save(path, &config.Config{}, []string{"shop", "data"}, "")
+			if err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			snap, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			list, err := snap.List(context.Background(), pods, tt.namespace)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if got := names(list); !slices.Equal(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		want     []string
+	}{
+		{
+			name:     "namespaces",
+			metadata: `{"namespaces": ["shop", "data"], "created": "2024-01-01T00:00:00Z"}`,
+			want:     []string{"shop", "data"},
+		},
+		{
+			name:     "legacy namespace",
+			metadata: `{"namespace": "shop", "created": "2024-01-01T00:00:00Z"}`,
+			want:     []string{"shop"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+			writeArchive(t, path, map[string]string{
+				configFileName:   `{"resources": []}`,
+				metadataFileName: tt.metadata,
+			})
+			snap, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !slices.Equal(snap.Namespaces, tt.want) {
+				t.Errorf("Load() namespaces = %v, want %v", snap.Namespaces, tt.want)
+			}
+		})
+	}
+}
+
+// writeArchive writes the files, keyed by name, to a gzipped tarball at path.
+func writeArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer f.Close()
+	gzipWriter := gzip.NewWriter(f)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		err = tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))})
+		if err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		_, err = tarWriter.Write([]byte(content))
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	err = tarWriter.Close()
+	if err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		t.Fatalf("failed to close compression: %v", err)
+	}
+}
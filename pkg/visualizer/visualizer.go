@@ -3,6 +3,11 @@ package visualizer
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/AyCarlito/kube-visualization/pkg/client"
 	"github.com/AyCarlito/kube-visualization/pkg/config"
@@ -10,50 +15,374 @@ import (
 	"github.com/AyCarlito/kube-visualization/pkg/logger"
 )
 
+// Publisher publishes a connected graph.
+type Publisher func(g *graph.Grapher) error
+
+// Cluster is a Kubernetes cluster to visualize, along with the configuration and namespaces to visualize it with.
+type Cluster struct {
+	Name          string
+	Lister        client.Lister
+	Configuration *config.Config
+	Namespaces    []string
+	// AllNamespaces is set when the namespaces are every namespace in the cluster.
+	AllNamespaces bool
+}
+
+// OptFunc is a function that mutates a visualizerOpts.
+type OptFunc func(*visualizerOpts)
+
+// visualizerOpts are the configuration options for the Visualizer.
+type visualizerOpts struct {
+	publisher     Publisher
+	clusters      []Cluster
+	focusResource string
+	focusName     string
+	concurrency   int
+	allNamespaces bool
+}
+
+// defaultOpts return the default configuration options for a Visualizer.
+func defaultOpts() visualizerOpts {
+	return visualizerOpts{
+		publisher:     nil,
+		clusters:      nil,
+		focusResource: "",
+		focusName:     "",
+		concurrency:   1,
+		allNamespaces: false,
+	}
+}
+
+// WithPublisher returns an optFunc to mutate the publisher configuration option of the Visualizer.
+// By default, the graph is written to the output file.
+func WithPublisher(p Publisher) OptFunc {
+	return func(o *visualizerOpts) {
+		o.publisher = p
+	}
+}
+
+// WithClusters returns an optFunc to mutate the clusters configuration option of the Visualizer.
+// When set, each of the clusters is visualized in the same graph, in place of the client, configuration and
+// namespaces of the Visualizer.
+func WithClusters(c []Cluster) OptFunc {
+	return func(o *visualizerOpts) {
+		o.clusters = c
+	}
+}
+
+// WithFocus returns an optFunc to mutate the focusResource and focusName configuration options of the Visualizer.
+// When set, only the objects of the resource with the name, and the objects related to them, are visualized. The
+// resource is referenced as by kubectl e.g. "deploy".
+func WithFocus(resource, name string) OptFunc {
+	return func(o *visualizerOpts) {
+		o.focusResource = resource
+		o.focusName = name
+	}
+}
+
+// WithConcurrency returns an optFunc to mutate the concurrency configuration option of the Visualizer.
+// The concurrency is the greatest number of lists made at once when gathering objects, which the client.Lister must
+// be safe for. By default, one list is made at a time.
+func WithConcurrency(c int) OptFunc {
+	return func(o *visualizerOpts) {
+		o.concurrency = max(c, 1)
+	}
+}
+
+// WithAllNamespaces returns an optFunc to mutate the allNamespaces configuration option of the Visualizer.
+// When set, the namespaces are every namespace in the cluster, so a client.AllNamespacesLister lists each namespaced
+// resource once across them all, rather than once for each namespace.
+func WithAllNamespaces(all bool) OptFunc {
+	return func(o *visualizerOpts) {
+		o.allNamespaces = all
+	}
+}
+
 // Visualizer can list namespaced resources in a Kubernetes cluster and generate graphical representations of them.
 type Visualizer struct {
 	ctx            context.Context
-	client         *client.Client
+	client         client.Lister
 	configuration  config.Config
 	grapher        *graph.Grapher
-	namespace      string
+	namespaces     []string
 	outputFilePath string
+	opts           visualizerOpts
 }
 
 // NewVisualizer returns a new *Visualizer.
-func NewVisualizer(ctx context.Context, c *client.Client, cfg *config.Config, g *graph.Grapher, ns, ofp string) *Visualizer {
+// Objects are gathered from the provided client.Lister, which need not be backed by a Kubernetes cluster, in each of
+// the namespaces.
+func NewVisualizer(ctx context.Context, c client.Lister, cfg *config.Config, g *graph.Grapher, ns []string, ofp string, opts ...OptFunc) *Visualizer {
+	o := defaultOpts()
+	for _, fn := range opts {
+		fn(&o)
+	}
 	return &Visualizer{
 		ctx:            ctx,
 		client:         c,
 		configuration:  *cfg,
 		grapher:        g,
-		namespace:      ns,
+		namespaces:     ns,
 		outputFilePath: ofp,
+		opts:           o,
 	}
 }
 
 // Visualize gathers namespaced resources in a Kubernetes cluster and generates a graphical representation of them.
 func (v *Visualizer) Visualize() error {
+	if len(v.opts.clusters) > 0 {
+		return v.visualizeClusters()
+	}
+	err := gather(v.ctx, v.client, v.configuration, v.namespaces, v.opts.allNamespaces, v.grapher, v.opts.concurrency)
+	if err != nil {
+		return err
+	}
+	return v.write()
+}
+
+// visualizeClusters gathers resources from each of the clusters, and generates a single graphical representation of
+// them all.
+func (v *Visualizer) visualizeClusters() error {
+	log := logger.LoggerFromContext(v.ctx)
+
+	graphers := make(map[string]*graph.Grapher)
+	for _, cluster := range v.opts.clusters {
+		log.Info("Gathering cluster: " + cluster.Name)
+		g := graph.NewGraph(nil, "")
+		err := gather(v.ctx, cluster.Lister, *cluster.Configuration, cluster.Namespaces, cluster.AllNamespaces, g, v.opts.concurrency)
+		if err != nil {
+			return fmt.Errorf("failed to gather cluster %s: %v", cluster.Name, err)
+		}
+		graphers[cluster.Name] = g
+	}
+
+	log.Info("Merging clusters")
+	v.grapher.Merge(graphers)
+	return v.write()
+}
+
+// Watch visualizes the namespaces, and then does so again whenever a value is received from changes, until the
+// context of the Visualizer is cancelled.
+// Changes are debounced, so that a burst of changes e.g. a rollout results in a single visualization once the burst
+// has been quiet for the debounce period. Failure to visualize after a change is logged rather than returned.
+func (v *Visualizer) Watch(changes <-chan struct{}, debounce time.Duration) error {
+	log := logger.LoggerFromContext(v.ctx)
+
+	err := v.Visualize()
+	if err != nil {
+		return err
+	}
+
+	// The timer only starts once a change has been received.
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-v.ctx.Done():
+			return nil
+		case <-changes:
+			timer.Reset(debounce)
+		case <-timer.C:
+			log.Info("Objects changed, visualizing")
+			err := v.Visualize()
+			if err != nil {
+				log.Error("failed to visualize: " + err.Error())
+			}
+		}
+	}
+}
+
+// Diff gathers namespaced resources from both before and the Visualizer's client, and generates a graphical
+// representation of the changes between them.
+// Before is gathered with its own configuration e.g. that recorded in a snapshot, so that the removal of resources no
+// longer configured is shown. The configuration of the Visualizer is used if it is nil.
+func (v *Visualizer) Diff(before client.Lister, beforeConfiguration *config.Config) error {
 	log := logger.LoggerFromContext(v.ctx)
 
-	v.grapher.Scaffold("Visualization", v.namespace, config.SortedUniqueRanks(v.configuration.Resources))
-	for _, resource := range v.configuration.Resources {
-		log.Info("Gathering: " + resource.String())
-		objects, err := v.client.List(v.ctx, resource.GroupVersionResource, v.namespace)
+	if beforeConfiguration == nil {
+		beforeConfiguration = &v.configuration
+	}
+
+	log.Info("Gathering before")
+	beforeGrapher := graph.NewGraph(nil, "")
+	err := gather(v.ctx, before, *beforeConfiguration, v.namespaces, v.opts.allNamespaces, beforeGrapher, v.opts.concurrency)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Gathering after")
+	afterGrapher := graph.NewGraph(nil, "")
+	err = gather(v.ctx, v.client, v.configuration, v.namespaces, v.opts.allNamespaces, afterGrapher, v.opts.concurrency)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Comparing before and after")
+	v.grapher.Diff(beforeGrapher, afterGrapher)
+	return v.write()
+}
+
+// list is a list of the objects of a resource in a namespace, or in every namespace, gathered by gather.
+type list struct {
+	resource  config.Resource
+	namespace string
+	// all is set for a list of the objects in every namespace.
+	all     bool
+	objects *unstructured.UnstructuredList
+}
+
+// gather scaffolds the grapher and populates it with the objects of the configured resources in the namespaces
+// listed by l.
+// Up to concurrency lists are made at once by a pool of workers. The grapher is only populated once every list has
+// completed, in the order of the configured resources, so that the graph is the same however the lists interleave.
+// When the namespaces are all of them, and l is a client.AllNamespacesLister, each namespaced resource is listed once
+// and its objects split between the namespaces. Objects in namespaces created since the namespaces were resolved are
+// left out, as when listing each namespace.
+func gather(ctx context.Context, l client.Lister, cfg config.Config, namespaces []string, all bool, g *graph.Grapher, concurrency int) error {
+	log := logger.LoggerFromContext(ctx)
+	start := time.Now()
+
+	g.Scaffold("Visualization", namespaces, config.SortedUniqueRanks(cfg.Resources))
+	allNamespacesLister, listAll := l.(client.AllNamespacesLister)
+	listAll = listAll && all
+	var lists []list
+	for _, resource := range cfg.Resources {
+		switch {
+		// Cluster-scoped resources do not belong to any namespace, and so are listed once across the cluster.
+		case !resource.IsNamespaced():
+			lists = append(lists, list{resource: resource})
+		case listAll:
+			lists = append(lists, list{resource: resource, all: true})
+		default:
+			for _, namespace := range namespaces {
+				lists = append(lists, list{resource: resource, namespace: namespace})
+			}
+		}
+	}
+
+	// The first failure cancels the lists yet to be made, as the visualization fails regardless.
+	ctx, cxl := context.WithCancel(ctx)
+	defer cxl()
+	var once sync.Once
+	var failure error
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(lists)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if ctx.Err() != nil {
+					continue
+				}
+				log.Info("Gathering: " + lists[i].resource.String())
+				var objects *unstructured.UnstructuredList
+				var err error
+				if lists[i].all {
+					objects, err = allNamespacesLister.ListAllNamespaces(ctx, lists[i].resource.GroupVersionResource)
+				} else {
+					objects, err = l.List(ctx, lists[i].resource.GroupVersionResource, lists[i].namespace)
+				}
+				// Users granted access to their namespaces alone are commonly not permitted to list cluster-scoped
+				// resources, which only supplement the namespaces, so are skipped rather than failing the visualization.
+				if apierrors.IsForbidden(err) && !lists[i].resource.IsNamespaced() {
+					log.Warn("Skipping cluster-scoped resource without permission to list it: " + lists[i].resource.String())
+					lists[i].objects = &unstructured.UnstructuredList{}
+					continue
+				}
+				if err != nil {
+					once.Do(func() {
+						failure = fmt.Errorf("failed to gather %s: %v", lists[i].resource.Resource, err)
+						cxl()
+					})
+					continue
+				}
+				lists[i].objects = objects
+			}
+		}()
+	}
+	for i := range lists {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	if failure != nil {
+		return failure
+	}
+	// The parent context may have been cancelled before any list failed.
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to gather objects: %v", ctx.Err())
+	}
+
+	for _, l := range lists {
+		if !l.all {
+			g.Populate(l.objects, l.resource)
+			continue
+		}
+		// Populate each namespace in turn, as if it had been listed on its own.
+		for _, namespace := range namespaces {
+			g.Populate(inNamespace(l.objects, namespace), l.resource)
+		}
+	}
+
+	// The labels of the namespaces are matched by the namespace selectors of NetworkPolicies. Without them e.g. when
+	// not permitted to list namespaces, the namespaces selected are drawn as a placeholder.
+	namespaceObjects, err := l.List(ctx, client.NamespacesGVR, "")
+	if err != nil {
+		log.Info("Unable to list namespaces, so NetworkPolicy namespace selectors are not resolved: " + err.Error())
+	} else {
+		g.LabelNamespaces(namespaceObjects)
+	}
+	log.Info("Gathered objects in " + time.Since(start).Round(time.Millisecond).String())
+	return nil
+}
+
+// inNamespace returns the objects of a list in a namespace.
+func inNamespace(objects *unstructured.UnstructuredList, namespace string) *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	for _, object := range objects.Items {
+		if object.GetNamespace() == namespace {
+			list.Items = append(list.Items, object)
+		}
+	}
+	return list
+}
+
+// write connects the populated grapher and publishes it, by default writing it to file.
+func (v *Visualizer) write() error {
+	log := logger.LoggerFromContext(v.ctx)
+
+	if v.opts.focusName != "" {
+		log.Info(fmt.Sprintf("Focusing on %s/%s", v.opts.focusResource, v.opts.focusName))
+		err := v.grapher.Focus(v.opts.focusResource, v.opts.focusName)
 		if err != nil {
-			return fmt.Errorf("failed to gather %s: %v", resource.Resource, err)
+			return err
 		}
-		v.grapher.Populate(objects, resource)
 	}
 
 	log.Info("Connecting related resources")
 	v.grapher.Connect()
 
-	// Write the string representation of the graph to file.
-	log.Info("Writing to file: " + v.outputFilePath)
-	err := v.grapher.WriteDotFile()
+	if v.opts.publisher != nil {
+		log.Info("Publishing graph")
+		err := v.opts.publisher(v.grapher)
+		if err != nil {
+			return fmt.Errorf("failed to publish graph: %v", err)
+		}
+		return nil
+	}
+
+	// Write the graph to file.
+	if v.outputFilePath == graph.Stdout {
+		log.Info("Writing to stdout")
+	} else {
+		log.Info("Writing to file: " + v.outputFilePath)
+	}
+	err := v.grapher.Write()
 	if err != nil {
-		return fmt.Errorf("failed to write graph to output dot file: %v", err)
+		return fmt.Errorf("failed to write graph to output file: %v", err)
 	}
 
 	return nil
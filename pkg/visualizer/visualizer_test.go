@@ -0,0 +1,156 @@
+package visualizer
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/AyCarlito/kube-visualization/pkg/client"
+	"github.com/AyCarlito/kube-visualization/pkg/config"
+	"github.com/AyCarlito/kube-visualization/pkg/graph"
+	"github.com/AyCarlito/kube-visualization/pkg/logger"
+)
+
+var (
+	pods  = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	nodes = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+)
+
+// fakeLister is a client.AllNamespacesLister of Pods in several namespaces and a Node, recording the lists made.
+type fakeLister struct {
+	mu    sync.Mutex
+	lists []string
+}
+
+// objects returns the objects of a GVR in a namespace, or in every namespace if all.
+func (f *fakeLister) objects(gvr schema.GroupVersionResource, namespace string, all bool) *unstructured.UnstructuredList {
+	var objects []unstructured.Unstructured
+	switch gvr {
+	case pods:
+		for _, ns := range []string{"a", "b", "z"} {
+			if all || ns == namespace {
+				objects = append(objects, newObject("Pod", ns, "web"))
+			}
+		}
+	case nodes:
+		objects = append(objects, newObject("Node", "", "node-1"))
+	}
+	return &unstructured.UnstructuredList{Items: objects}
+}
+
+func (f *fakeLister) List(ctx context.Context, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists = append(f.lists, gvr.Resource+"/"+namespace)
+	return f.objects(gvr, namespace, false), nil
+}
+
+func (f *fakeLister) ListAllNamespaces(ctx context.Context, gvr schema.GroupVersionResource) (*unstructured.UnstructuredList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists = append(f.lists, gvr.Resource+"/*")
+	return f.objects(gvr, "", true), nil
+}
+
+// newObject returns an object of a kind with the given namespace and name.
+func newObject(kind, namespace, name string) unstructured.Unstructured {
+	object := unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind(kind)
+	object.SetNamespace(namespace)
+	object.SetName(name)
+	return object
+}
+
+func TestGather(t *testing.T) {
+	clusterScoped := false
+	cfg := config.Config{Resources: []config.Resource{
+		{GroupVersionResource: pods, Rank: 10},
+		{GroupVersionResource: nodes, Rank: 20, Namespaced: &clusterScoped},
+	}}
+	tests := []struct {
+		name string
+		all  bool
+		// listAll is set when the lister can list every namespace at once.
+		listAll     bool
+		wantLists   []string
+		wantObjects []string
+	}{
+		{
+			name:        "each namespace is listed",
+			listAll:     true,
+			wantLists:   []string{"namespaces/", "nodes/", "pods/a", "pods/b"},
+			wantObjects: []string{"Node_node-1", "Pod_a/web", "Pod_b/web"},
+		},
+		{
+			name:        "every namespace is listed at once",
+			all:         true,
+			listAll:     true,
+			wantLists:   []string{"namespaces/", "nodes/", "pods/*"},
+			wantObjects: []string{"Node_node-1", "Pod_a/web", "Pod_b/web"},
+		},
+		{
+			name:        "each namespace is listed without an all namespaces lister",
+			all:         true,
+			wantLists:   []string{"namespaces/", "nodes/", "pods/a", "pods/b"},
+			wantObjects: []string{"Node_node-1", "Pod_a/web", "Pod_b/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logger.ContextWithLogger(context.Background(), zap.NewNop())
+			fake := &fakeLister{}
+			var l client.Lister = fake
+			if !tt.listAll {
+				l = client.ListerFunc(fake.List)
+			}
+			g := graph.NewGraph(nil, "")
+			err := gather(ctx, l, cfg, []string{"a", "b"}, tt.all, g, 2)
+			if err != nil {
+				t.Fatalf("gather() error = %v", err)
+			}
+
+			lists := slices.Sorted(slices.Values(fake.lists))
+			if !slices.Equal(lists, tt.wantLists) {
+				t.Errorf("gather() lists = %v, want %v", lists, tt.wantLists)
+			}
+			objects := slices.Sorted(maps.Keys(g.Objects()))
+			if !slices.Equal(objects, tt.wantObjects) {
+				t.Errorf("gather() objects = %v, want %v", objects, tt.wantObjects)
+			}
+		})
+	}
+}
+
+func TestInNamespace(t *testing.T) {
+	objects := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+		newObject("Pod", "a", "web"),
+		newObject("Pod", "b", "web"),
+		newObject("Pod", "a", "db"),
+	}}
+	tests := []struct {
+		namespace string
+		want      []string
+	}{
+		{namespace: "a", want: []string{"web", "db"}},
+		{namespace: "b", want: []string{"web"}},
+		{namespace: "z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			var names []string
+			for _, object := range inNamespace(objects, tt.namespace).Items {
+				names = append(names, object.GetName())
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("inNamespace() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}